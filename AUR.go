@@ -2,41 +2,51 @@ package libremotebuild
 
 // AURBuild build an AUR package
 type AURBuild struct {
-	LibRB
-	args          map[string]string
-	UploadType    UploadType
-	DisableCcache bool
+	JobBuilder
 }
 
 // NewAURBuild build an AUR package
 func (Librb LibRB) NewAURBuild(packageName string) *AURBuild {
-	return &AURBuild{
-		LibRB: Librb,
-		args: map[string]string{
-			AURPackage: packageName,
-		},
+	aurBuild := &AURBuild{
+		JobBuilder: *Librb.NewJobBuilder(JobAUR),
 	}
+
+	aurBuild.WithArg(AURPackage, packageName)
+	return aurBuild
 }
 
 // WithoutCcache disables ccache
 func (aurBuild *AURBuild) WithoutCcache() *AURBuild {
-	aurBuild.DisableCcache = true
+	aurBuild.JobBuilder.WithoutCcache()
 	return aurBuild
 }
 
-// WithDmanager use dmnager for uplaod
-func (aurBuild *AURBuild) WithDmanager(username, token, host, namespace string) {
-	aurBuild.UploadType = DataManagerUploadType
-	aurBuild.args[DMToken] = token
-	aurBuild.args[DMUser] = username
-	aurBuild.args[DMHost] = host
+// WithIdempotencyKey use key for creating the job. Creating
+// the job multiple times with the same key results in one job
+func (aurBuild *AURBuild) WithIdempotencyKey(key string) *AURBuild {
+	aurBuild.JobBuilder.WithIdempotencyKey(key)
+	return aurBuild
+}
 
-	if len(namespace) > 0 {
-		aurBuild.args[DMNamespace] = namespace
-	}
+// WithUploadTarget upload the result to target
+func (aurBuild *AURBuild) WithUploadTarget(target UploadTarget) *AURBuild {
+	aurBuild.JobBuilder.WithUploadTarget(target)
+	return aurBuild
 }
 
-// CreateJob build AUR package
-func (aurBuild *AURBuild) CreateJob() (*AddJobResponse, error) {
-	return aurBuild.LibRB.AddJob(JobAUR, aurBuild.UploadType, aurBuild.args, aurBuild.DisableCcache)
+// WithPacmanRepo add the built packages to repo after the build
+func (aurBuild *AURBuild) WithPacmanRepo(repo PacmanRepo) *AURBuild {
+	aurBuild.JobBuilder.WithPacmanRepo(repo)
+	return aurBuild
+}
+
+// WithWebhook notify webhookURL about events of the job
+func (aurBuild *AURBuild) WithWebhook(webhookURL, secret string, events ...JobEventType) *AURBuild {
+	aurBuild.JobBuilder.WithWebhook(webhookURL, secret, events...)
+	return aurBuild
+}
+
+// WithDmanager use dmnager for uplaod
+func (aurBuild *AURBuild) WithDmanager(username, token, host, namespace string) {
+	aurBuild.JobBuilder.WithDmanager(username, token, host, namespace)
 }
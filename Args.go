@@ -19,3 +19,72 @@ const (
 const (
 	AURPackage = "REPO"
 )
+
+// Git keys
+const (
+	// GitURL url of the repository
+	GitURL = "GIT_URL"
+
+	// GitBranch branch to build
+	GitBranch = "GIT_BRANCH"
+
+	// GitTag tag to build
+	GitTag = "GIT_TAG"
+
+	// GitCommit commit to build
+	GitCommit = "GIT_COMMIT"
+
+	// GitSubdir directory containing the PKGBUILD
+	GitSubdir = "GIT_SUBDIR"
+
+	// GitSubmodules "true" to checkout submodules
+	GitSubmodules = "GIT_SUBMODULES"
+)
+
+// Local build keys
+const (
+	// LocalUploadID ID of the uploaded sources
+	LocalUploadID = "UPLOAD_ID"
+)
+
+// S3 keys
+const (
+	// S3Endpoint url of the object storage
+	S3Endpoint = "S3_ENDPOINT"
+
+	// S3Bucket bucket to upload to
+	S3Bucket = "S3_BUCKET"
+
+	// S3Prefix key prefix of the uploaded packages
+	S3Prefix = "S3_PREFIX"
+
+	// S3Region region of the bucket
+	S3Region = "S3_REGION"
+
+	// S3AccessKey access key ID
+	S3AccessKey = "S3_ACCESS_KEY"
+
+	// S3SecretKey secret access key
+	S3SecretKey = "S3_SECRET_KEY"
+
+	// S3SecretRef name of a server side secret holding the credentials
+	S3SecretRef = "S3_SECRET_REF"
+
+	// S3PathStyle "true" to use path style addressing
+	S3PathStyle = "S3_PATH_STYLE"
+)
+
+// Pacman repository keys
+const (
+	// PacmanRepoName name of the repository database
+	PacmanRepoName = "PACMAN_REPO_NAME"
+
+	// PacmanRepoSign "true" to sign the database
+	PacmanRepoSign = "PACMAN_REPO_SIGN"
+
+	// PacmanRepoKey key used for signing
+	PacmanRepoKey = "PACMAN_REPO_KEY"
+
+	// PacmanRepoRemoveOld "true" to remove superseded packages
+	PacmanRepoRemoveOld = "PACMAN_REPO_REMOVE_OLD"
+)
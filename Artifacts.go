@@ -0,0 +1,241 @@
+package libremotebuild
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+var (
+	// ErrInvalidArtifactName artifact name can't be used as file name
+	ErrInvalidArtifactName = errors.New("invalid artifact name")
+)
+
+// ListArtifacts lists the artifacts of a job
+func (librb LibRB) ListArtifacts(jobID uint) ([]Artifact, error) {
+	return librb.ListArtifactsContext(context.Background(), jobID)
+}
+
+// ListArtifactsContext lists the artifacts of a job using ctx
+func (librb LibRB) ListArtifactsContext(ctx context.Context, jobID uint) ([]Artifact, error) {
+	var response ListArtifactsResponse
+
+	// Do http request
+	resp, err := librb.NewRequest(EPJobArtifacts, JobRequest{
+		JobID: jobID,
+	}).WithContext(ctx).
+		WithAuthFromConfig().
+		WithMethod(GET).
+		Do(&response)
+
+	// Return new error on ... error
+	if err != nil || resp.Status == ResponseError {
+		return nil, NewErrorFromResponse(resp, err)
+	}
+
+	return response.Artifacts, nil
+}
+
+// DownloadArtifact streams an artifact into w
+func (librb LibRB) DownloadArtifact(jobID uint, artifact Artifact, w io.Writer) (int64, error) {
+	return librb.DownloadArtifactContext(context.Background(), jobID, artifact, w, 0)
+}
+
+// DownloadArtifactContext writes an artifact into w starting at offset,
+// which allows resuming downloads. The artifact is downloaded into a part
+// file in os.TempDir() first, so its checksum and, if LibRB has a
+// SignatureVerifier, its signature get verified before any data is
+// written to w. The part file is kept if the download fails, a retry
+// resumes it using a Range request. Returns the amount of bytes written
+func (librb LibRB) DownloadArtifactContext(ctx context.Context, jobID uint, artifact Artifact, w io.Writer, offset int64) (int64, error) {
+	file := spoolPath(jobID, artifact)
+	if err := librb.DownloadArtifactToFileContext(ctx, jobID, artifact, file); err != nil {
+		return 0, err
+	}
+	defer os.Remove(file)
+
+	f, err := os.Open(file)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	return io.Copy(w, f)
+}
+
+// spoolPath returns the file DownloadArtifactContext downloads artifact
+// into. It only depends on the artifact, so failed downloads get resumed
+func spoolPath(jobID uint, artifact Artifact) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d/%s/%s", jobID, artifact.Name, artifact.SHA256)))
+	return filepath.Join(os.TempDir(), "remotebuild-"+hex.EncodeToString(sum[:16]))
+}
+
+// DownloadArtifactToFile downloads an artifact into file
+func (librb LibRB) DownloadArtifactToFile(jobID uint, artifact Artifact, file string) error {
+	return librb.DownloadArtifactToFileContext(context.Background(), jobID, artifact, file)
+}
+
+// DownloadArtifactToFileContext downloads an artifact into file using ctx. Data
+// is written to file+PartialSuffix first, which gets resumed if it exists. The
+// part file is renamed to file after its checksum and, if LibRB has a
+// SignatureVerifier, its signature were verified
+func (librb LibRB) DownloadArtifactToFileContext(ctx context.Context, jobID uint, artifact Artifact, file string) error {
+	part := file + PartialSuffix
+
+	if err := librb.downloadPart(ctx, jobID, artifact, part); err != nil {
+		return err
+	}
+
+	if err := librb.verifySignature(ctx, artifact, part); err != nil {
+		os.Remove(part)
+		return err
+	}
+
+	return os.Rename(part, file)
+}
+
+// downloadPart downloads artifact into part, resuming already downloaded
+// data. The file is removed if its checksum doesn't match
+func (librb LibRB) downloadPart(ctx context.Context, jobID uint, artifact Artifact, part string) error {
+	f, err := os.OpenFile(part, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// Hash already downloaded data
+	hasher := sha256.New()
+	offset, err := io.Copy(hasher, f)
+	if err != nil {
+		return err
+	}
+
+	// Start over if the file is bigger than expected
+	if offset > artifact.Size {
+		if offset, err = restartFile(f, hasher); err != nil {
+			return err
+		}
+	}
+
+	if offset < artifact.Size {
+		_, err := librb.downloadArtifact(ctx, jobID, artifact.Name, offset, f, hasher, func() error {
+			// Server sends the whole file
+			_, err := restartFile(f, hasher)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := verifyChecksum(artifact, hasher); err != nil {
+		f.Close()
+		os.Remove(part)
+		return err
+	}
+
+	return nil
+}
+
+// DownloadArtifacts downloads all artifacts of a job into dir
+func (librb LibRB) DownloadArtifacts(jobID uint, dir string) ([]Artifact, error) {
+	return librb.DownloadArtifactsContext(context.Background(), jobID, dir)
+}
+
+// DownloadArtifactsContext downloads all artifacts of a job into dir using ctx
+func (librb LibRB) DownloadArtifactsContext(ctx context.Context, jobID uint, dir string) ([]Artifact, error) {
+	artifacts, err := librb.ListArtifactsContext(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, artifact := range artifacts {
+		file, err := ArtifactPath(dir, artifact)
+		if err != nil {
+			return nil, err
+		}
+
+		if err = librb.DownloadArtifactToFileContext(ctx, jobID, artifact, file); err != nil {
+			return nil, fmt.Errorf("%s: %w", artifact.Name, err)
+		}
+	}
+
+	return artifacts, nil
+}
+
+// ArtifactPath returns the path of artifact inside dir. Fails for
+// names which would point outside of dir
+func ArtifactPath(dir string, artifact Artifact) (string, error) {
+	name := filepath.Base(artifact.Name)
+	if name != artifact.Name || name == "." || name == ".." || name == string(filepath.Separator) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidArtifactName, artifact.Name)
+	}
+
+	return filepath.Join(dir, name), nil
+}
+
+// downloadArtifact copies the artifact starting at offset into w and hasher.
+// If the server ignores the offset, onRestart is called before writing,
+// allowing to reset w and hasher
+func (librb LibRB) downloadArtifact(ctx context.Context, jobID uint, name string, offset int64, w io.Writer, hasher hash.Hash, onRestart func() error) (int64, error) {
+	req := librb.NewRequest(EPJobArtifactDownload, ArtifactRequest{
+		JobID: jobID,
+		Name:  name,
+	}).WithContext(ctx).
+		WithAuthFromConfig().
+		WithNoBodyClose().
+		WithMethod(GET)
+
+	if offset > 0 {
+		req.WithHeader("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := req.Do(nil)
+	if err != nil || resp.Status == ResponseError {
+		return 0, NewErrorFromResponse(resp, err)
+	}
+	defer resp.Response.Body.Close()
+
+	if offset > 0 && resp.HTTPCode != http.StatusPartialContent {
+		if err := onRestart(); err != nil {
+			return 0, err
+		}
+	}
+
+	return io.Copy(io.MultiWriter(w, hasher), resp.Response.Body)
+}
+
+// restartFile truncates f and resets hasher
+func restartFile(f *os.File, hasher hash.Hash) (int64, error) {
+	hasher.Reset()
+
+	if err := f.Truncate(0); err != nil {
+		return 0, err
+	}
+
+	return f.Seek(0, io.SeekStart)
+}
+
+// verifyChecksum compares the sum of hasher with the checksum of artifact
+func verifyChecksum(artifact Artifact, hasher hash.Hash) error {
+	if sum := hex.EncodeToString(hasher.Sum(nil)); sum != artifact.SHA256 {
+		return &VerificationError{
+			Artifact: artifact,
+			Err:      ErrChecksumMismatch,
+			Details:  fmt.Sprintf("expected %s, got %s", artifact.SHA256, sum),
+		}
+	}
+
+	return nil
+}
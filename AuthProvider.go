@@ -0,0 +1,297 @@
+package libremotebuild
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNoCredentials provider has no credentials available
+	ErrNoCredentials = errors.New("no credentials available")
+)
+
+// AuthProvider provides the authorization for a request.
+// It gets consulted for every attempt of a request
+type AuthProvider interface {
+	Authorization(ctx context.Context) (*Authorization, error)
+}
+
+// RefreshableAuthProvider an AuthProvider able to renew its
+// authorization after the server rejected it
+type RefreshableAuthProvider interface {
+	AuthProvider
+	Refresh(ctx context.Context, rejected *Authorization) error
+}
+
+// WithAuthProvider use p for all authorized requests
+func (librb *LibRB) WithAuthProvider(p AuthProvider) *LibRB {
+	librb.AuthProvider = p
+	return librb
+}
+
+// defaultAuth returns the AuthProvider used by Request.WithAuthFromConfig
+func (librb *LibRB) defaultAuth() AuthProvider {
+	if librb.AuthProvider != nil {
+		return librb.AuthProvider
+	}
+
+	return &SessionAuth{
+		LibRB: *librb,
+	}
+}
+
+// SessionAuth uses the session token of the config. Rejected
+// sessions are renewed using the TokenRefresher of LibRB
+type SessionAuth struct {
+	LibRB LibRB
+}
+
+// Authorization implements AuthProvider
+func (sessionAuth *SessionAuth) Authorization(ctx context.Context) (*Authorization, error) {
+	auth := sessionAuth.LibRB.Config.GetBearerAuth()
+	return &auth, nil
+}
+
+// Refresh implements RefreshableAuthProvider
+func (sessionAuth *SessionAuth) Refresh(ctx context.Context, rejected *Authorization) error {
+	return sessionAuth.LibRB.RefreshSession(ctx, rejected.Palyoad)
+}
+
+// StaticAuth always uses the same authorization
+type StaticAuth Authorization
+
+// StaticToken returns an AuthProvider using token as bearer token
+func StaticToken(token string) StaticAuth {
+	return StaticAuth{
+		Type:    Bearer,
+		Palyoad: token,
+	}
+}
+
+// Authorization implements AuthProvider
+func (staticAuth StaticAuth) Authorization(ctx context.Context) (*Authorization, error) {
+	auth := Authorization(staticAuth)
+	return &auth, nil
+}
+
+// EnvAuth reads a bearer token from an environment variable
+type EnvAuth struct {
+	Variable string
+}
+
+// Authorization implements AuthProvider
+func (envAuth EnvAuth) Authorization(ctx context.Context) (*Authorization, error) {
+	token := strings.TrimSpace(os.Getenv(envAuth.Variable))
+	if len(token) == 0 {
+		return nil, fmt.Errorf("%w: $%s is empty", ErrNoCredentials, envAuth.Variable)
+	}
+
+	return &Authorization{
+		Type:    Bearer,
+		Palyoad: token,
+	}, nil
+}
+
+// TokenFileAuth reads a bearer token from a file. The
+// file is read again as soon as it changes
+type TokenFileAuth struct {
+	Path string
+
+	mx      sync.Mutex
+	token   string
+	modTime time.Time
+	size    int64
+}
+
+// NewTokenFileAuth returns a new TokenFileAuth reading from path
+func NewTokenFileAuth(path string) *TokenFileAuth {
+	return &TokenFileAuth{
+		Path: path,
+	}
+}
+
+// Authorization implements AuthProvider
+func (fileAuth *TokenFileAuth) Authorization(ctx context.Context) (*Authorization, error) {
+	fileAuth.mx.Lock()
+	defer fileAuth.mx.Unlock()
+
+	stat, err := os.Stat(fileAuth.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Reload on change
+	if len(fileAuth.token) == 0 || !stat.ModTime().Equal(fileAuth.modTime) || stat.Size() != fileAuth.size {
+		b, err := ioutil.ReadFile(fileAuth.Path)
+		if err != nil {
+			return nil, err
+		}
+
+		token := strings.TrimSpace(string(b))
+		if len(token) == 0 {
+			return nil, fmt.Errorf("%w: %s is empty", ErrNoCredentials, fileAuth.Path)
+		}
+
+		fileAuth.token = token
+		fileAuth.modTime = stat.ModTime()
+		fileAuth.size = stat.Size()
+	}
+
+	return &Authorization{
+		Type:    Bearer,
+		Palyoad: fileAuth.token,
+	}, nil
+}
+
+// Refresh implements RefreshableAuthProvider. Forces
+// the file to be read again on the next request
+func (fileAuth *TokenFileAuth) Refresh(ctx context.Context, rejected *Authorization) error {
+	fileAuth.mx.Lock()
+	defer fileAuth.mx.Unlock()
+
+	fileAuth.token = ""
+	return nil
+}
+
+// CommandAuth obtains a bearer token by running an external helper, similar to
+// a git credential helper. The helper receives 'protocol', 'host' and 'username'
+// lines on stdin and prints either the bare token or 'token=<token>' (or
+// 'password=<token>') lines. The token is cached for CacheFor
+type CommandAuth struct {
+	Command  string
+	Args     []string
+	URL      string
+	Username string
+	CacheFor time.Duration
+
+	mx       sync.Mutex
+	token    string
+	cachedAt time.Time
+}
+
+// NewCommandAuth returns a new CommandAuth running command
+// with args for the server and user of config
+func NewCommandAuth(config *RequestConfig, command string, args ...string) *CommandAuth {
+	return &CommandAuth{
+		Command:  command,
+		Args:     args,
+		URL:      config.URL,
+		Username: config.Username,
+		CacheFor: 5 * time.Minute,
+	}
+}
+
+// Authorization implements AuthProvider
+func (cmdAuth *CommandAuth) Authorization(ctx context.Context) (*Authorization, error) {
+	cmdAuth.mx.Lock()
+	defer cmdAuth.mx.Unlock()
+
+	if len(cmdAuth.token) == 0 || time.Since(cmdAuth.cachedAt) > cmdAuth.CacheFor {
+		token, err := cmdAuth.run(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		cmdAuth.token = token
+		cmdAuth.cachedAt = time.Now()
+	}
+
+	return &Authorization{
+		Type:    Bearer,
+		Palyoad: cmdAuth.token,
+	}, nil
+}
+
+// Refresh implements RefreshableAuthProvider. Drops
+// the cached token to run the helper again
+func (cmdAuth *CommandAuth) Refresh(ctx context.Context, rejected *Authorization) error {
+	cmdAuth.mx.Lock()
+	defer cmdAuth.mx.Unlock()
+
+	cmdAuth.token = ""
+	return nil
+}
+
+func (cmdAuth *CommandAuth) run(ctx context.Context) (string, error) {
+	var stdin, stdout, stderr bytes.Buffer
+
+	// Describe the requested credentials
+	if u, err := url.Parse(cmdAuth.URL); err == nil {
+		fmt.Fprintf(&stdin, "protocol=%s\nhost=%s\n", u.Scheme, u.Host)
+	}
+	if len(cmdAuth.Username) > 0 {
+		fmt.Fprintf(&stdin, "username=%s\n", cmdAuth.Username)
+	}
+	stdin.WriteString("\n")
+
+	cmd := exec.CommandContext(ctx, cmdAuth.Command, cmdAuth.Args...)
+	cmd.Stdin = &stdin
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("credential helper %s: %w: %s", cmdAuth.Command, err, strings.TrimSpace(stderr.String()))
+	}
+
+	token := parseHelperOutput(stdout.String())
+	if len(token) == 0 {
+		return "", fmt.Errorf("%w: credential helper %s returned no token", ErrNoCredentials, cmdAuth.Command)
+	}
+
+	return token, nil
+}
+
+// parseHelperOutput returns the token printed by a credential helper
+func parseHelperOutput(output string) string {
+	var token, password, first string
+	var hasKeys bool
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 {
+			continue
+		}
+		if len(first) == 0 {
+			first = line
+		}
+
+		i := strings.Index(line, "=")
+		if i < 0 {
+			continue
+		}
+
+		switch line[:i] {
+		case "token":
+			token = line[i+1:]
+		case "password":
+			password = line[i+1:]
+		case "protocol", "host", "path", "username":
+		default:
+			continue
+		}
+		hasKeys = true
+	}
+
+	switch {
+	case len(token) > 0:
+		return token
+	case len(password) > 0:
+		return password
+	case hasKeys:
+		return ""
+	}
+
+	// Bare token. Might contain '=' padding
+	return first
+}
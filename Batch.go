@@ -0,0 +1,237 @@
+package libremotebuild
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrDependencyCycle dependencies of a batch contain a cycle
+	ErrDependencyCycle = errors.New("dependency cycle")
+	// ErrUnknownDependency a job depends on a job not part of the batch
+	ErrUnknownDependency = errors.New("unknown dependency")
+	// ErrDuplicateJobName a job name is used twice in a batch
+	ErrDuplicateJobName = errors.New("duplicate job name")
+)
+
+// BatchBuild submits multiple jobs ordered by their dependencies
+type BatchBuild struct {
+	librb LibRB
+	jobs  []batchJob
+}
+
+type batchJob struct {
+	name      string
+	spec      JobSpec
+	dependsOn []string
+}
+
+// Batch a submitted BatchBuild
+type Batch struct {
+	librb LibRB
+	// Order names of the jobs in submission order
+	Order []string
+	// Jobs IDs of the submitted jobs by their names
+	Jobs map[string]uint
+}
+
+// NewBatch creates a new empty batch
+func (librb LibRB) NewBatch() *BatchBuild {
+	return &BatchBuild{
+		librb: librb,
+	}
+}
+
+// Add adds a job named name which starts after all jobs in dependsOn are done
+func (batch *BatchBuild) Add(name string, spec JobSpec, dependsOn ...string) *BatchBuild {
+	batch.jobs = append(batch.jobs, batchJob{
+		name:      name,
+		spec:      spec,
+		dependsOn: dependsOn,
+	})
+
+	return batch
+}
+
+// Validate checks that the dependencies form a DAG
+func (batch *BatchBuild) Validate() error {
+	_, err := batch.Order()
+	return err
+}
+
+// Order returns the job names in topological order. Jobs
+// without dependencies between them keep the order they were added
+func (batch *BatchBuild) Order() ([]string, error) {
+	index := make(map[string]int, len(batch.jobs))
+	for i, job := range batch.jobs {
+		if _, ok := index[job.name]; ok {
+			return nil, fmt.Errorf("%w: %s", ErrDuplicateJobName, job.name)
+		}
+		index[job.name] = i
+	}
+
+	// Count unresolved dependencies and collect dependents
+	pending := make([]int, len(batch.jobs))
+	dependents := make([][]int, len(batch.jobs))
+	for i, job := range batch.jobs {
+		for _, dep := range job.dependsOn {
+			j, ok := index[dep]
+			if !ok {
+				return nil, fmt.Errorf("%w: %s depends on %s", ErrUnknownDependency, job.name, dep)
+			}
+
+			pending[i]++
+			dependents[j] = append(dependents[j], i)
+		}
+	}
+
+	order := make([]string, 0, len(batch.jobs))
+	done := make([]bool, len(batch.jobs))
+
+	// Repeatedly take the first job without pending dependencies
+	for len(order) < len(batch.jobs) {
+		next := -1
+		for i := range batch.jobs {
+			if !done[i] && pending[i] == 0 {
+				next = i
+				break
+			}
+		}
+
+		if next == -1 {
+			var cycle []string
+			for i, job := range batch.jobs {
+				if !done[i] {
+					cycle = append(cycle, job.name)
+				}
+			}
+			return nil, fmt.Errorf("%w between %v", ErrDependencyCycle, cycle)
+		}
+
+		done[next] = true
+		order = append(order, batch.jobs[next].name)
+		for _, dependent := range dependents[next] {
+			pending[dependent]--
+		}
+	}
+
+	return order, nil
+}
+
+// Submit submits all jobs in topological order. If submitting a job fails,
+// the returned Batch contains all jobs submitted so far
+func (batch *BatchBuild) Submit(ctx context.Context) (*Batch, error) {
+	order, err := batch.Order()
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make(map[string]batchJob, len(batch.jobs))
+	for _, job := range batch.jobs {
+		jobs[job.name] = job
+	}
+
+	result := &Batch{
+		librb: batch.librb,
+		Jobs:  make(map[string]uint, len(order)),
+	}
+
+	for _, name := range order {
+		job := jobs[name]
+
+		request, err := job.spec.JobRequest(ctx)
+		if err != nil {
+			return result, fmt.Errorf("%s: %w", name, err)
+		}
+
+		for _, dep := range job.dependsOn {
+			request.DependsOn = append(request.DependsOn, result.Jobs[dep])
+		}
+
+		resp, err := batch.librb.SubmitJobContext(ctx, *request)
+		if err != nil {
+			return result, fmt.Errorf("%s: %w", name, err)
+		}
+
+		result.Order = append(result.Order, name)
+		result.Jobs[name] = resp.ID
+	}
+
+	return result, nil
+}
+
+// Info returns the JobInfo of all jobs of the batch by their names
+func (batch *Batch) Info(ctx context.Context) (map[string]*JobInfo, error) {
+	infos := make(map[string]*JobInfo, len(batch.Jobs))
+
+	for _, name := range batch.Order {
+		info, err := batch.librb.JobInfoContext(ctx, batch.Jobs[name])
+		if err != nil {
+			return infos, fmt.Errorf("%s: %w", name, err)
+		}
+
+		infos[name] = info
+	}
+
+	return infos, nil
+}
+
+// Done returns true if all jobs reached a terminal state
+func (batch *Batch) Done(ctx context.Context) (bool, error) {
+	infos, err := batch.Info(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	for _, info := range infos {
+		if !info.Status.IsTerminal() {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// Wait waits until all jobs reached a terminal state. Returns
+// the first *JobError if a job failed or was cancelled
+func (batch *Batch) Wait(ctx context.Context, opts *WaitOptions) (map[string]*JobInfo, error) {
+	infos := make(map[string]*JobInfo, len(batch.Jobs))
+
+	var jobErr error
+	for _, name := range batch.Order {
+		info, err := batch.librb.WaitForJob(ctx, batch.Jobs[name], opts)
+		if info != nil {
+			infos[name] = info
+		}
+
+		var e *JobError
+		if errors.As(err, &e) {
+			if jobErr == nil {
+				jobErr = fmt.Errorf("%s: %w", name, err)
+			}
+		} else if err != nil {
+			return infos, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	return infos, jobErr
+}
+
+// Cancel cancels all jobs which aren't finished yet
+func (batch *Batch) Cancel(ctx context.Context) error {
+	infos, err := batch.Info(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range batch.Order {
+		if info := infos[name]; info != nil && !info.Status.IsTerminal() {
+			if err := batch.librb.CancelJobContext(ctx, info.ID); err != nil && !errors.Is(err, ErrInvalidState) {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+		}
+	}
+
+	return nil
+}
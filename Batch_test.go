@@ -0,0 +1,68 @@
+package libremotebuild
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestBatchOrder(t *testing.T) {
+	type job struct {
+		name string
+		deps []string
+	}
+
+	tests := []struct {
+		name    string
+		jobs    []job
+		want    []string
+		wantErr error
+	}{
+		{
+			name: "independent jobs keep order",
+			jobs: []job{{"a", nil}, {"b", nil}, {"c", nil}},
+			want: []string{"a", "b", "c"},
+		},
+		{
+			name: "dependencies first",
+			jobs: []job{{"app", []string{"lib", "tool"}}, {"lib", []string{"base"}}, {"tool", nil}, {"base", nil}},
+			want: []string{"tool", "base", "lib", "app"},
+		},
+		{
+			name:    "cycle",
+			jobs:    []job{{"a", []string{"c"}}, {"b", []string{"a"}}, {"c", []string{"b"}}, {"d", nil}},
+			wantErr: ErrDependencyCycle,
+		},
+		{
+			name:    "self dependency",
+			jobs:    []job{{"a", []string{"a"}}},
+			wantErr: ErrDependencyCycle,
+		},
+		{
+			name:    "unknown dependency",
+			jobs:    []job{{"a", []string{"missing"}}},
+			wantErr: ErrUnknownDependency,
+		},
+		{
+			name:    "duplicate name",
+			jobs:    []job{{"a", nil}, {"a", nil}},
+			wantErr: ErrDuplicateJobName,
+		},
+	}
+
+	for _, test := range tests {
+		batch := LibRB{}.NewBatch()
+		for _, j := range test.jobs {
+			batch.Add(j.name, LibRB{}.NewAURBuild(j.name), j.deps...)
+		}
+
+		order, err := batch.Order()
+		if !errors.Is(err, test.wantErr) {
+			t.Errorf("%s: expected error %v, got %v", test.name, test.wantErr, err)
+			continue
+		}
+		if test.wantErr == nil && !reflect.DeepEqual(order, test.want) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.want, order)
+		}
+	}
+}
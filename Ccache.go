@@ -1,14 +1,49 @@
 package libremotebuild
 
+import "context"
+
 // ClearCcache clear ccache on server
 func (librb LibRB) ClearCcache() (string, error) {
-	resp, err := librb.NewRequest(EPCcacheClear, nil).WithAuthFromConfig().WithMethod(POST).Do(nil)
-	return resp.Message, err
+	return librb.ClearCcacheContext(context.Background())
+}
+
+// ClearCcacheContext clear ccache on server using ctx
+func (librb LibRB) ClearCcacheContext(ctx context.Context) (string, error) {
+	resp, err := librb.NewRequest(EPCcacheClear, nil).
+		WithContext(ctx).
+		WithAuthFromConfig().
+		WithMethod(POST).
+		Do(nil)
+
+	if err != nil || resp.Status == ResponseError {
+		var message string
+		if resp != nil {
+			message = resp.Message
+		}
+		return message, NewErrorFromResponse(resp, err)
+	}
+
+	return resp.Message, nil
 }
 
 // QueryCcache get ccache stats
 func (librb LibRB) QueryCcache() (StringResponse, error) {
-	var resp StringResponse
-	_, err := librb.NewRequest(EPCcacheStats, nil).WithAuthFromConfig().WithMethod(GET).Do(&resp)
-	return resp, err
+	return librb.QueryCcacheContext(context.Background())
+}
+
+// QueryCcacheContext get ccache stats using ctx
+func (librb LibRB) QueryCcacheContext(ctx context.Context) (StringResponse, error) {
+	var response StringResponse
+
+	resp, err := librb.NewRequest(EPCcacheStats, nil).
+		WithContext(ctx).
+		WithAuthFromConfig().
+		WithMethod(GET).
+		Do(&response)
+
+	if err != nil || resp.Status == ResponseError {
+		return response, NewErrorFromResponse(resp, err)
+	}
+
+	return response, nil
 }
@@ -0,0 +1,129 @@
+package libremotebuild
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// ClientOptions options for the http client shared by all requests of a LibRB
+type ClientOptions struct {
+	// Timeout for a whole request including reading the body. Keep it
+	// 0 if log streams are used, since they would get cut off
+	Timeout time.Duration
+
+	DialTimeout           time.Duration
+	KeepAlive             time.Duration
+	TLSHandshakeTimeout   time.Duration
+	ResponseHeaderTimeout time.Duration
+	IdleConnTimeout       time.Duration
+
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	MaxConnsPerHost     int
+
+	// Proxy to use. Defaults to http.ProxyFromEnvironment
+	Proxy func(*http.Request) (*url.URL, error)
+
+	// RootCAs to verify the server with. Uses the system pool if nil
+	RootCAs *x509.CertPool
+	// Certificates client certificates for mTLS
+	Certificates []tls.Certificate
+
+	// Transport if set, used instead of building a new
+	// transport. All transport related options are ignored
+	Transport http.RoundTripper
+}
+
+// DefaultClientOptions returns the default options
+func DefaultClientOptions() ClientOptions {
+	return ClientOptions{
+		DialTimeout:         30 * time.Second,
+		KeepAlive:           30 * time.Second,
+		TLSHandshakeTimeout: 10 * time.Second,
+		IdleConnTimeout:     90 * time.Second,
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 10,
+		Proxy:               http.ProxyFromEnvironment,
+	}
+}
+
+// NewClient creates a new http client using opts. IgnoreCert
+// of config is applied once while creating the client
+func (opts ClientOptions) NewClient(config *RequestConfig) *http.Client {
+	transport := opts.Transport
+
+	if transport == nil {
+		transport = &http.Transport{
+			Proxy: opts.Proxy,
+			DialContext: (&net.Dialer{
+				Timeout:   opts.DialTimeout,
+				KeepAlive: opts.KeepAlive,
+			}).DialContext,
+			TLSClientConfig: &tls.Config{
+				InsecureSkipVerify: config != nil && config.IgnoreCert,
+				RootCAs:            opts.RootCAs,
+				Certificates:       opts.Certificates,
+			},
+			TLSHandshakeTimeout:   opts.TLSHandshakeTimeout,
+			ResponseHeaderTimeout: opts.ResponseHeaderTimeout,
+			IdleConnTimeout:       opts.IdleConnTimeout,
+			MaxIdleConns:          opts.MaxIdleConns,
+			MaxIdleConnsPerHost:   opts.MaxIdleConnsPerHost,
+			MaxConnsPerHost:       opts.MaxConnsPerHost,
+			ForceAttemptHTTP2:     true,
+		}
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   opts.Timeout,
+	}
+}
+
+// NewLibRBWithOptions create new libRB using a client built from opts
+func NewLibRBWithOptions(config *RequestConfig, opts ClientOptions) *LibRB {
+	return &LibRB{
+		Config:      config,
+		Client:      opts.NewClient(config),
+		RetryPolicy: DefaultRetryPolicy(),
+	}
+}
+
+// WithHTTPClient use client for all requests
+func (librb *LibRB) WithHTTPClient(client *http.Client) *LibRB {
+	librb.Client = client
+	return librb
+}
+
+// WithTransport use rt for all requests
+func (librb *LibRB) WithTransport(rt http.RoundTripper) *LibRB {
+	opts := DefaultClientOptions()
+	opts.Transport = rt
+	librb.Client = opts.NewClient(librb.Config)
+	return librb
+}
+
+// LoadCertPool creates a certpool containing the
+// certificates of all given PEM files
+func LoadCertPool(pemFiles ...string) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+
+	for _, file := range pemFiles {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		if !pool.AppendCertsFromPEM(b) {
+			return nil, errors.New("No certificates found in " + file)
+		}
+	}
+
+	return pool, nil
+}
@@ -3,6 +3,9 @@ package libremotebuild
 import (
 	"errors"
 	"fmt"
+	"net/http"
+	"strings"
+	"time"
 )
 
 var (
@@ -10,8 +13,42 @@ var (
 	ErrInvalidResponseHeaders = errors.New("Invalid response headers")
 	// ErrResponseError response returned an error
 	ErrResponseError = errors.New("response returned an error")
+	// ErrJobFailed job finished in JobFailed state
+	ErrJobFailed = errors.New("job failed")
+	// ErrJobCancelled job finished in JobCancelled state
+	ErrJobCancelled = errors.New("job cancelled")
 )
 
+// Errors derived from the http code and
+// status message of a response
+var (
+	// ErrUnauthorized missing or invalid session
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrJobNotFound requested job doesn't exist
+	ErrJobNotFound = errors.New("job not found")
+	// ErrInvalidState job can't be set into the requested state
+	ErrInvalidState = errors.New("invalid job state")
+	// ErrServerUnavailable server or its proxy is unavailable
+	ErrServerUnavailable = errors.New("server unavailable")
+	// ErrRateLimited too many requests
+	ErrRateLimited = errors.New("rate limited")
+)
+
+// JobError error for a job which didn't finish successfully
+type JobError struct {
+	Job *JobInfo
+	Err error
+}
+
+func (jobErr *JobError) Error() string {
+	return fmt.Sprintf("Job %d: %s", jobErr.Job.ID, jobErr.Err.Error())
+}
+
+// Unwrap returns ErrJobFailed or ErrJobCancelled
+func (jobErr *JobError) Unwrap() error {
+	return jobErr.Err
+}
+
 // ResponseErr response error
 type ResponseErr struct {
 	Response *RestRequestResponse
@@ -29,6 +66,76 @@ func (reserr *ResponseErr) Error() string {
 	return "Unexpected error"
 }
 
+// Unwrap returns the underlying error
+func (reserr *ResponseErr) Unwrap() error {
+	return reserr.Err
+}
+
+// Is reports whether the response matches target. Allows
+// errors.Is(err, ErrUnauthorized) and similar checks
+func (reserr *ResponseErr) Is(target error) bool {
+	kind := reserr.Kind()
+	return kind != nil && kind == target
+}
+
+// Kind returns the error derived from the http code and status
+// message of the response. Job specific kinds are only derived
+// from responses carrying valid protocol headers, a 404 of a proxy
+// isn't a missing job. Returns nil if no kind matches
+func (reserr *ResponseErr) Kind() error {
+	if reserr.Response == nil {
+		return nil
+	}
+
+	switch reserr.Response.HTTPCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ErrUnauthorized
+	case http.StatusTooManyRequests:
+		return ErrRateLimited
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return ErrServerUnavailable
+	}
+
+	if errors.Is(reserr.Err, ErrInvalidResponseHeaders) {
+		return nil
+	}
+
+	msg := strings.ToLower(reserr.Response.Message)
+
+	switch reserr.Response.HTTPCode {
+	case http.StatusNotFound:
+		// Other resources like artifacts can be missing too
+		if len(msg) == 0 || strings.Contains(msg, "job") {
+			return ErrJobNotFound
+		}
+		return nil
+	case http.StatusConflict, http.StatusUnprocessableEntity:
+		return ErrInvalidState
+	}
+
+	// Fall back to the status message
+	switch {
+	case strings.Contains(msg, "unauthorized"), strings.Contains(msg, "invalid token"), strings.Contains(msg, "not logged in"):
+		return ErrUnauthorized
+	case strings.Contains(msg, "job not found"):
+		return ErrJobNotFound
+	case strings.Contains(msg, "invalid state"):
+		return ErrInvalidState
+	}
+
+	return nil
+}
+
+// RetryAfter returns the time the server asked to
+// wait before sending the next request
+func (reserr *ResponseErr) RetryAfter() (time.Duration, bool) {
+	if reserr.Response == nil || reserr.Response.Headers == nil {
+		return 0, false
+	}
+
+	return parseRetryAfter(reserr.Response.Headers.Get(HeaderRetryAfter))
+}
+
 // NewErrorFromResponse return error from response
 func NewErrorFromResponse(r *RestRequestResponse, err ...error) *ResponseErr {
 	var (
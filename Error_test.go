@@ -0,0 +1,83 @@
+package libremotebuild
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestResponseErrKind(t *testing.T) {
+	tests := []struct {
+		code    int
+		message string
+		want    error
+	}{
+		{http.StatusUnauthorized, "", ErrUnauthorized},
+		{http.StatusForbidden, "", ErrUnauthorized},
+		{http.StatusNotFound, "", ErrJobNotFound},
+		{http.StatusNotFound, "Job not found", ErrJobNotFound},
+		{http.StatusNotFound, "Artifact not found", nil},
+		{http.StatusConflict, "", ErrInvalidState},
+		{http.StatusTooManyRequests, "", ErrRateLimited},
+		{http.StatusBadGateway, "", ErrServerUnavailable},
+		{http.StatusServiceUnavailable, "", ErrServerUnavailable},
+		{http.StatusGatewayTimeout, "", ErrServerUnavailable},
+		{http.StatusBadRequest, "Job not found", ErrJobNotFound},
+		{http.StatusBadRequest, "Invalid token", ErrUnauthorized},
+		{http.StatusBadRequest, "invalid state", ErrInvalidState},
+		{http.StatusBadRequest, "something else", nil},
+	}
+
+	for _, test := range tests {
+		err := NewErrorFromResponse(&RestRequestResponse{
+			HTTPCode: test.code,
+			Status:   ResponseError,
+			Message:  test.message,
+		})
+
+		if kind := err.Kind(); kind != test.want {
+			t.Errorf("%d %q: expected %v, got %v", test.code, test.message, test.want, kind)
+		}
+		if test.want != nil && !errors.Is(err, test.want) {
+			t.Errorf("%d %q: errors.Is failed for %v", test.code, test.message, test.want)
+		}
+		if !errors.Is(err, ErrResponseError) {
+			t.Errorf("%d %q: expected ErrResponseError", test.code, test.message)
+		}
+	}
+}
+
+func TestResponseErrKindWithoutHeaders(t *testing.T) {
+	tests := []struct {
+		code int
+		want error
+	}{
+		{http.StatusNotFound, nil},
+		{http.StatusConflict, nil},
+		{http.StatusTooManyRequests, ErrRateLimited},
+		{http.StatusServiceUnavailable, ErrServerUnavailable},
+	}
+
+	for _, test := range tests {
+		err := NewErrorFromResponse(&RestRequestResponse{
+			HTTPCode: test.code,
+			Message:  "job not found",
+		}, ErrInvalidResponseHeaders)
+
+		if kind := err.Kind(); kind != test.want {
+			t.Errorf("%d: expected %v, got %v", test.code, test.want, kind)
+		}
+	}
+}
+
+func TestResponseErrWithoutResponse(t *testing.T) {
+	cause := errors.New("connection refused")
+	err := NewErrorFromResponse(nil, cause)
+
+	if !errors.Is(err, cause) {
+		t.Error("expected cause to be unwrapped")
+	}
+	if errors.Is(err, ErrResponseError) || errors.Is(err, ErrServerUnavailable) {
+		t.Error("unexpected kind for transport error")
+	}
+}
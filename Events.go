@@ -0,0 +1,382 @@
+package libremotebuild
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// JobEventType type of a job event
+type JobEventType string
+
+// Job events
+const (
+	// JobCreatedEvent a job was added to the queue
+	JobCreatedEvent JobEventType = "created"
+	// JobStateChangedEvent the state of a job changed
+	JobStateChangedEvent JobEventType = "state-changed"
+	// JobPositionChangedEvent the queue position of a waiting job changed
+	JobPositionChangedEvent JobEventType = "position-changed"
+	// JobFinishedEvent a job reached a terminal state
+	JobFinishedEvent JobEventType = "finished"
+)
+
+// Reconnect delays used by EventStream
+const (
+	DefaultEventReconnectDelay    = 1 * time.Second
+	DefaultEventMaxReconnectDelay = 30 * time.Second
+)
+
+// JobEvent a change of a job
+type JobEvent struct {
+	// ID of the event. Used as Last-Event-ID on reconnects
+	ID    string       `json:"id"`
+	Type  JobEventType `json:"type"`
+	Time  time.Time    `json:"time"`
+	JobID uint         `json:"job"`
+	// State of the job after the event
+	State JobState `json:"state"`
+	// PrevState state before a JobStateChangedEvent
+	PrevState JobState `json:"prevstate"`
+	// Position in the queue. 0 if not waiting
+	Position uint `json:"pos"`
+	// Info of the job at the time of the event. Optional
+	Info *JobInfo `json:"info,omitempty"`
+}
+
+// EventFilter limits the events of an EventStream.
+// Empty filters match everything
+type EventFilter struct {
+	JobIDs []uint
+	Types  []JobEventType
+}
+
+// Matches returns true if event passes the filter
+func (filter EventFilter) Matches(event JobEvent) bool {
+	if len(filter.JobIDs) > 0 && !containsUint(filter.JobIDs, event.JobID) {
+		return false
+	}
+
+	if len(filter.Types) > 0 {
+		for _, t := range filter.Types {
+			if t == event.Type {
+				return true
+			}
+		}
+		return false
+	}
+
+	return true
+}
+
+// EventStream receives job events pushed by the server. Dropped
+// connections are re-established using the ID of the last event
+type EventStream struct {
+	librb  LibRB
+	filter EventFilter
+
+	reconnectDelay    time.Duration
+	maxReconnectDelay time.Duration
+
+	events chan JobEvent
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mx          sync.Mutex
+	lastEventID string
+	skipped     uint
+	err         error
+}
+
+// JobEvents starts receiving job events matching filter. If lastEventID
+// is set, only events after it are sent. The returned stream runs
+// until it gets closed using Close() or ctx is done
+func (librb LibRB) JobEvents(ctx context.Context, filter EventFilter, lastEventID string) *EventStream {
+	ctx, cancel := context.WithCancel(ctx)
+
+	stream := &EventStream{
+		librb:             librb,
+		filter:            filter,
+		reconnectDelay:    DefaultEventReconnectDelay,
+		maxReconnectDelay: DefaultEventMaxReconnectDelay,
+		events:            make(chan JobEvent),
+		cancel:            cancel,
+		done:              make(chan struct{}),
+		lastEventID:       lastEventID,
+	}
+
+	go stream.run(ctx)
+	return stream
+}
+
+// OpenJobEvents opens a single event stream. The body
+// of the returned response has to be closed by the caller
+func (librb LibRB) OpenJobEvents(ctx context.Context, filter EventFilter, lastEventID string) (*RestRequestResponse, error) {
+	request := librb.NewRequest(EPJobEvents, JobEventsRequest{
+		JobIDs: filter.JobIDs,
+		Types:  filter.Types,
+	}).WithContext(ctx).
+		WithAuthFromConfig().
+		WithNoBodyClose().
+		WithMethod(GET)
+
+	if len(lastEventID) > 0 {
+		request.WithHeader(HeaderLastEventID, lastEventID)
+	}
+
+	// Do http request
+	resp, err := request.Do(nil)
+
+	// Return new error on ... error
+	if err != nil || resp.Status == ResponseError {
+		return nil, NewErrorFromResponse(resp, err)
+	}
+
+	return resp, nil
+}
+
+// Events returns the channel receiving the events. It
+// gets closed once the stream is closed or failed
+func (stream *EventStream) Events() <-chan JobEvent {
+	return stream.events
+}
+
+// Err returns the error which stopped the stream.
+// Only valid after Events() was closed
+func (stream *EventStream) Err() error {
+	stream.mx.Lock()
+	defer stream.mx.Unlock()
+	return stream.err
+}
+
+// LastEventID returns the ID of the last delivered event
+func (stream *EventStream) LastEventID() string {
+	stream.mx.Lock()
+	defer stream.mx.Unlock()
+	return stream.lastEventID
+}
+
+// Skipped returns the amount of events which couldn't be decoded
+func (stream *EventStream) Skipped() uint {
+	stream.mx.Lock()
+	defer stream.mx.Unlock()
+	return stream.skipped
+}
+
+// Close stops the stream and waits for it to exit
+func (stream *EventStream) Close() error {
+	stream.cancel()
+	<-stream.done
+
+	err := stream.Err()
+	if err == context.Canceled {
+		return nil
+	}
+
+	return err
+}
+
+func (stream *EventStream) run(ctx context.Context) {
+	defer close(stream.done)
+	defer close(stream.events)
+
+	delay := stream.reconnectDelay
+
+	for {
+		received, err := stream.stream(ctx)
+		if ctx.Err() != nil {
+			stream.setErr(ctx.Err())
+			return
+		}
+
+		// Server rejected the request eg. invalid session
+		if isServerError(err) {
+			stream.setErr(err)
+			return
+		}
+
+		if received {
+			delay = stream.reconnectDelay
+		}
+
+		select {
+		case <-ctx.Done():
+			stream.setErr(ctx.Err())
+			return
+		case <-time.After(delay):
+		}
+
+		if delay *= 2; delay > stream.maxReconnectDelay {
+			delay = stream.maxReconnectDelay
+		}
+	}
+}
+
+// stream reads the event stream once. Returns true
+// if at least one event was received
+func (stream *EventStream) stream(ctx context.Context) (bool, error) {
+	resp, err := stream.librb.OpenJobEvents(ctx, stream.filter, stream.LastEventID())
+	if err != nil {
+		return false, err
+	}
+	defer resp.Response.Body.Close()
+
+	var received bool
+	reader := newEventReader(resp.Response.Body, stream.LastEventID())
+
+	defer func() {
+		// Use the reconnect delay requested by the server
+		if reader.retry > 0 {
+			stream.reconnectDelay = reader.retry
+		}
+
+		stream.mx.Lock()
+		stream.skipped += reader.skipped
+		stream.mx.Unlock()
+	}()
+
+	for {
+		event, err := reader.next()
+		if err != nil {
+			// Skip events without data or
+			// which couldn't be decoded
+			stream.mx.Lock()
+			stream.lastEventID = reader.lastID
+			stream.mx.Unlock()
+
+			if err == io.EOF {
+				err = nil
+			}
+			return received, err
+		}
+
+		received = true
+
+		// Filter again in case the server ignores the filter
+		if stream.filter.Matches(*event) {
+			select {
+			case <-ctx.Done():
+				return received, ctx.Err()
+			case stream.events <- *event:
+			}
+		}
+
+		stream.mx.Lock()
+		stream.lastEventID = event.ID
+		stream.mx.Unlock()
+	}
+}
+
+func (stream *EventStream) setErr(err error) {
+	stream.mx.Lock()
+	stream.err = err
+	stream.mx.Unlock()
+}
+
+// eventReader parses a text/event-stream
+type eventReader struct {
+	reader *bufio.Reader
+	// idBuffer value of the last id field. Kept across
+	// events as defined by the SSE spec
+	idBuffer string
+	// lastID ID of the last dispatched event, including
+	// events without data and undecodable ones
+	lastID string
+	// retry reconnect delay requested by the server
+	retry time.Duration
+	// skipped amount of events which couldn't be decoded
+	skipped uint
+}
+
+func newEventReader(r io.Reader, lastID string) *eventReader {
+	return &eventReader{
+		reader:   bufio.NewReader(r),
+		idBuffer: lastID,
+		lastID:   lastID,
+	}
+}
+
+// next returns the next complete event. Events without data, eg.
+// keepalives, and events which can't be decoded are skipped but
+// still update lastID. Incomplete events are dropped, they will
+// be resent after reconnecting
+func (er *eventReader) next() (*JobEvent, error) {
+	var eventType string
+	var data []string
+
+	for {
+		line, err := er.reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+
+		line = strings.TrimRight(line, "\r\n")
+
+		// Dispatch the event on an empty line
+		if len(line) == 0 {
+			er.lastID = er.idBuffer
+
+			if len(data) == 0 {
+				eventType = ""
+				continue
+			}
+
+			var event JobEvent
+			if err := json.Unmarshal([]byte(strings.Join(data, "\n")), &event); err != nil {
+				er.skipped++
+				eventType, data = "", nil
+				continue
+			}
+
+			event.ID = er.lastID
+			if event.Info != nil {
+				event.Info.Args = RedactArgs(event.Info.Args)
+			}
+			if len(eventType) > 0 {
+				event.Type = JobEventType(eventType)
+			}
+
+			return &event, nil
+		}
+
+		// Comment
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value := line, ""
+		if i := strings.IndexByte(line, ':'); i >= 0 {
+			field, value = line[:i], strings.TrimPrefix(line[i+1:], " ")
+		}
+
+		switch field {
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				er.idBuffer = value
+			}
+		case "event":
+			eventType = value
+		case "data":
+			data = append(data, value)
+		case "retry":
+			if ms, err := strconv.Atoi(value); err == nil && ms > 0 {
+				er.retry = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+}
+
+func containsUint(s []uint, v uint) bool {
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,49 @@
+package libremotebuild
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEventReader(t *testing.T) {
+	input := ": keepalive\n" +
+		"retry: 250\n\n" +
+		"id: 1\nevent: created\ndata: {\"job\": 3,\n" +
+		"data: \"state\": 0}\n\n" +
+		"id: 2\r\ndata: {\"type\": \"finished\", \"job\": 3}\r\n\r\n" +
+		"id: 3\ndata: not json\n\n" +
+		"data: {\"type\": \"created\", \"job\": 4}\n\n" +
+		"id: 5\n\n" +
+		"id: 6\ndata: {\"job\": 4"
+
+	reader := newEventReader(strings.NewReader(input), "0")
+
+	event, err := reader.next()
+	if err != nil || event.ID != "1" || event.Type != JobCreatedEvent || event.JobID != 3 {
+		t.Fatalf("unexpected event %+v: %v", event, err)
+	}
+	if reader.retry != 250*time.Millisecond {
+		t.Fatalf("unexpected retry %v", reader.retry)
+	}
+
+	event, err = reader.next()
+	if err != nil || event.ID != "2" || event.Type != JobFinishedEvent {
+		t.Fatalf("unexpected event %+v: %v", event, err)
+	}
+
+	// Undecodable events are skipped, the last id is kept
+	event, err = reader.next()
+	if err != nil || event.ID != "3" || event.JobID != 4 || reader.skipped != 1 {
+		t.Fatalf("unexpected event %+v: %v", event, err)
+	}
+
+	// Incomplete events are dropped, events without data update the id
+	if event, err = reader.next(); err != io.EOF {
+		t.Fatalf("expected EOF, got %+v: %v", event, err)
+	}
+	if reader.lastID != "5" {
+		t.Fatalf("unexpected last id %q", reader.lastID)
+	}
+}
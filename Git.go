@@ -0,0 +1,138 @@
+package libremotebuild
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+var (
+	commitRegex = regexp.MustCompile("^[0-9a-fA-F]{7,40}$")
+	scpRegex    = regexp.MustCompile(`^[\w.-]+@[\w.-]+:.+$`)
+)
+
+// GitBuild build a package from a git repository
+type GitBuild struct {
+	JobBuilder
+}
+
+// NewGitBuild build a package from the repository at repoURL
+func (librb LibRB) NewGitBuild(repoURL string) *GitBuild {
+	gitBuild := &GitBuild{
+		JobBuilder: *librb.NewJobBuilder(JobGit),
+	}
+
+	gitBuild.WithArg(GitURL, repoURL)
+	return gitBuild
+}
+
+// WithBranch build the head of branch
+func (gitBuild *GitBuild) WithBranch(branch string) *GitBuild {
+	gitBuild.setRef(GitBranch, branch)
+	return gitBuild
+}
+
+// WithTag build tag
+func (gitBuild *GitBuild) WithTag(tag string) *GitBuild {
+	gitBuild.setRef(GitTag, tag)
+	return gitBuild
+}
+
+// WithCommit build commit
+func (gitBuild *GitBuild) WithCommit(commit string) *GitBuild {
+	gitBuild.setRef(GitCommit, commit)
+	return gitBuild
+}
+
+// WithSubdirectory use the PKGBUILD in dir, relative to the repository root
+func (gitBuild *GitBuild) WithSubdirectory(dir string) *GitBuild {
+	gitBuild.WithArg(GitSubdir, dir)
+	return gitBuild
+}
+
+// WithSubmodules checkout submodules
+func (gitBuild *GitBuild) WithSubmodules() *GitBuild {
+	gitBuild.WithArg(GitSubmodules, "true")
+	return gitBuild
+}
+
+// WithoutCcache disables ccache
+func (gitBuild *GitBuild) WithoutCcache() *GitBuild {
+	gitBuild.JobBuilder.WithoutCcache()
+	return gitBuild
+}
+
+// WithIdempotencyKey use key for creating the job
+func (gitBuild *GitBuild) WithIdempotencyKey(key string) *GitBuild {
+	gitBuild.JobBuilder.WithIdempotencyKey(key)
+	return gitBuild
+}
+
+// WithUploadTarget upload the result to target
+func (gitBuild *GitBuild) WithUploadTarget(target UploadTarget) *GitBuild {
+	gitBuild.JobBuilder.WithUploadTarget(target)
+	return gitBuild
+}
+
+// WithPacmanRepo add the built packages to repo after the build
+func (gitBuild *GitBuild) WithPacmanRepo(repo PacmanRepo) *GitBuild {
+	gitBuild.JobBuilder.WithPacmanRepo(repo)
+	return gitBuild
+}
+
+// WithWebhook notify webhookURL about events of the job
+func (gitBuild *GitBuild) WithWebhook(webhookURL, secret string, events ...JobEventType) *GitBuild {
+	gitBuild.JobBuilder.WithWebhook(webhookURL, secret, events...)
+	return gitBuild
+}
+
+// setRef sets the ref to build. Only one of branch, tag or commit can be used
+func (gitBuild *GitBuild) setRef(key, value string) {
+	for _, k := range []string{GitBranch, GitTag, GitCommit} {
+		delete(gitBuild.args, k)
+	}
+
+	gitBuild.WithArg(key, value)
+}
+
+// validateGitArgs validates the args of a JobGit
+func validateGitArgs(args map[string]string) error {
+	repoURL := args[GitURL]
+
+	// Allow scp like syntax (git@host:repo.git)
+	if !scpRegex.MatchString(repoURL) {
+		u, err := url.Parse(repoURL)
+		if err != nil || len(u.Scheme) == 0 || (len(u.Host) == 0 && u.Scheme != "file") {
+			return fmt.Errorf("%w: invalid repository url %q", ErrInvalidJobArgs, repoURL)
+		}
+	}
+
+	var refs int
+	for _, key := range []string{GitBranch, GitTag, GitCommit} {
+		if len(args[key]) > 0 {
+			refs++
+		}
+	}
+	if refs > 1 {
+		return fmt.Errorf("%w: only one of branch, tag or commit can be set", ErrInvalidJobArgs)
+	}
+
+	if commit, ok := args[GitCommit]; ok && !commitRegex.MatchString(commit) {
+		return fmt.Errorf("%w: invalid commit %q", ErrInvalidJobArgs, commit)
+	}
+
+	if subdir, ok := args[GitSubdir]; ok {
+		clean := path.Clean(subdir)
+		if path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+			return fmt.Errorf("%w: subdirectory %q leaves the repository", ErrInvalidJobArgs, subdir)
+		}
+	}
+
+	if submodules, ok := args[GitSubmodules]; ok && submodules != "true" && submodules != "false" {
+		return fmt.Errorf("%w: %s has to be true or false", ErrInvalidJobArgs, GitSubmodules)
+	}
+
+	return nil
+}
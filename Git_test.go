@@ -0,0 +1,47 @@
+package libremotebuild
+
+import "testing"
+
+func TestGitBuildValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		build   func(LibRB) *GitBuild
+		wantErr bool
+	}{
+		{"https", func(l LibRB) *GitBuild { return l.NewGitBuild("https://example.com/pkg.git") }, false},
+		{"scp syntax", func(l LibRB) *GitBuild { return l.NewGitBuild("git@example.com:pkg.git") }, false},
+		{"missing url", func(l LibRB) *GitBuild { return l.NewGitBuild("") }, true},
+		{"relative url", func(l LibRB) *GitBuild { return l.NewGitBuild("pkg.git") }, true},
+		{"branch", func(l LibRB) *GitBuild { return l.NewGitBuild("https://example.com/pkg.git").WithBranch("main") }, false},
+		{"last ref wins", func(l LibRB) *GitBuild {
+			return l.NewGitBuild("https://example.com/pkg.git").WithBranch("main").WithTag("v1.0")
+		}, false},
+		{"commit", func(l LibRB) *GitBuild { return l.NewGitBuild("https://example.com/pkg.git").WithCommit("a1b2c3d") }, false},
+		{"invalid commit", func(l LibRB) *GitBuild { return l.NewGitBuild("https://example.com/pkg.git").WithCommit("main") }, true},
+		{"subdirectory", func(l LibRB) *GitBuild {
+			return l.NewGitBuild("https://example.com/pkg.git").WithSubdirectory("packages/foo").WithSubmodules()
+		}, false},
+		{"shared options", func(l LibRB) *GitBuild {
+			return l.NewGitBuild("https://example.com/pkg.git").WithoutCcache().WithBranch("main")
+		}, false},
+		{"escaping subdirectory", func(l LibRB) *GitBuild {
+			return l.NewGitBuild("https://example.com/pkg.git").WithSubdirectory("../foo")
+		}, true},
+	}
+
+	for _, test := range tests {
+		req, err := test.build(LibRB{}).BuildRequest()
+		if (err != nil) != test.wantErr {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+			continue
+		}
+
+		if err == nil && req.Type != JobGit {
+			t.Errorf("%s: expected JobGit, got %s", test.name, req.Type)
+		}
+	}
+
+	if ParseJobType("buildGit") != JobGit || JobGit.String() != "buildGit" {
+		t.Error("JobGit not resolvable")
+	}
+}
@@ -0,0 +1,115 @@
+package libremotebuild
+
+import (
+	"bufio"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// ignorePattern a single .gitignore style pattern
+type ignorePattern struct {
+	regex   *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// ignoreMatcher matches paths against .gitignore style patterns.
+// Later patterns take precedence over earlier ones
+type ignoreMatcher struct {
+	patterns []ignorePattern
+}
+
+// readIgnoreFile adds all patterns of file. A missing file is no error
+func (matcher *ignoreMatcher) readIgnoreFile(file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		matcher.add(scanner.Text())
+	}
+
+	return scanner.Err()
+}
+
+// add adds a pattern. Empty lines and comments are ignored
+func (matcher *ignoreMatcher) add(line string) {
+	line = strings.TrimRight(line, " \t\r")
+	if len(line) == 0 || strings.HasPrefix(line, "#") {
+		return
+	}
+
+	var pattern ignorePattern
+
+	if strings.HasPrefix(line, "!") {
+		pattern.negate = true
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		pattern.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+
+	// Patterns containing a slash are relative to the root,
+	// others match the name at any depth
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	expr := globToRegex(line)
+	if !anchored {
+		expr = "(.*/)?" + expr
+	}
+
+	pattern.regex = regexp.MustCompile("^" + expr + "$")
+	matcher.patterns = append(matcher.patterns, pattern)
+}
+
+// match returns true if the slash separated relPath is ignored
+func (matcher *ignoreMatcher) match(relPath string, isDir bool) bool {
+	var ignored bool
+
+	for _, pattern := range matcher.patterns {
+		if pattern.dirOnly && !isDir {
+			continue
+		}
+
+		if pattern.regex.MatchString(relPath) {
+			ignored = !pattern.negate
+		}
+	}
+
+	return ignored
+}
+
+// globToRegex converts a glob supporting *, ** and ? into a regex
+func globToRegex(glob string) string {
+	var sb strings.Builder
+
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			sb.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			sb.WriteString(".*")
+			i++
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	return sb.String()
+}
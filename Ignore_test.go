@@ -0,0 +1,46 @@
+package libremotebuild
+
+import "testing"
+
+func TestIgnoreMatcher(t *testing.T) {
+	var matcher ignoreMatcher
+	for _, line := range []string{
+		"# comment",
+		"",
+		"*.pkg.tar.*",
+		"src/",
+		"/pkg",
+		"docs/**/*.md",
+		"!docs/keep/README.md",
+		"*.log",
+		"!important.log",
+	} {
+		matcher.add(line)
+	}
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"PKGBUILD", false, false},
+		{"foo-1.0-1-x86_64.pkg.tar.zst", false, true},
+		{"sub/foo-1.0-1-any.pkg.tar.xz", false, true},
+		{"src", true, true},
+		{"a/src", true, true},
+		{"src", false, false},
+		{"pkg", true, true},
+		{"a/pkg", true, false},
+		{"docs/a/b/c.md", false, true},
+		{"docs/c.md", false, true},
+		{"docs/keep/README.md", false, false},
+		{"build.log", false, true},
+		{"important.log", false, false},
+	}
+
+	for _, test := range tests {
+		if got := matcher.match(test.path, test.isDir); got != test.want {
+			t.Errorf("%s (dir: %v): expected %v, got %v", test.path, test.isDir, test.want, got)
+		}
+	}
+}
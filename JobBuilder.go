@@ -0,0 +1,193 @@
+package libremotebuild
+
+import "context"
+
+// JobSpec describes a job which can be submitted
+type JobSpec interface {
+	// JobRequest returns the request creating the job
+	JobRequest(ctx context.Context) (*AddJobRequest, error)
+}
+
+// JobBuilder builds a job of any registered JobType. Builders for
+// specific job types embed it and wrap its options to return themselves
+type JobBuilder struct {
+	LibRB
+	Type           JobType
+	args           map[string]string
+	UploadType     UploadType
+	UploadTarget   UploadTarget
+	Repository     *PacmanRepo
+	Webhooks       []Webhook
+	DisableCcache  bool
+	IdempotencyKey string
+}
+
+// NewJobBuilder creates a builder for a job of type jobType
+func (librb LibRB) NewJobBuilder(jobType JobType) *JobBuilder {
+	return &JobBuilder{
+		LibRB: librb,
+		Type:  jobType,
+		args:  make(map[string]string),
+	}
+}
+
+// WithArg sets an arg
+func (builder *JobBuilder) WithArg(key, value string) *JobBuilder {
+	builder.args[key] = value
+	return builder
+}
+
+// WithArgs sets multiple args
+func (builder *JobBuilder) WithArgs(args map[string]string) *JobBuilder {
+	for k, v := range args {
+		builder.args[k] = v
+	}
+
+	return builder
+}
+
+// Args returns a copy of the args
+func (builder *JobBuilder) Args() map[string]string {
+	args := make(map[string]string, len(builder.args))
+	for k, v := range builder.args {
+		args[k] = v
+	}
+
+	return args
+}
+
+// WithUploadType upload the result to ut. The settings of
+// ut have to be passed as args. Prefer WithUploadTarget
+func (builder *JobBuilder) WithUploadType(ut UploadType) *JobBuilder {
+	builder.UploadType = ut
+	builder.UploadTarget = nil
+	return builder
+}
+
+// WithUploadTarget upload the result to target
+func (builder *JobBuilder) WithUploadTarget(target UploadTarget) *JobBuilder {
+	builder.UploadType = target.UploadType()
+	builder.UploadTarget = target
+	return builder
+}
+
+// WithPacmanRepo add the built packages to repo after the build
+func (builder *JobBuilder) WithPacmanRepo(repo PacmanRepo) *JobBuilder {
+	builder.Repository = &repo
+	return builder
+}
+
+// WithWebhook notify webhookURL about events of the job. Payloads are signed
+// using secret. Only JobFinishedEvent is sent if no events are passed
+func (builder *JobBuilder) WithWebhook(webhookURL, secret string, events ...JobEventType) *JobBuilder {
+	builder.Webhooks = append(builder.Webhooks, Webhook{
+		URL:    webhookURL,
+		Secret: secret,
+		Events: events,
+	})
+	return builder
+}
+
+// WithoutCcache disables ccache
+func (builder *JobBuilder) WithoutCcache() *JobBuilder {
+	builder.DisableCcache = true
+	return builder
+}
+
+// WithIdempotencyKey use key for creating the job. Creating
+// the job multiple times with the same key results in one job
+func (builder *JobBuilder) WithIdempotencyKey(key string) *JobBuilder {
+	builder.IdempotencyKey = key
+	return builder
+}
+
+// WithDmanager use dmnager for uplaod
+func (builder *JobBuilder) WithDmanager(username, token, host, namespace string) *JobBuilder {
+	return builder.WithUploadTarget(DataManagerTarget{
+		Username:  username,
+		Token:     token,
+		Host:      host,
+		Namespace: namespace,
+	})
+}
+
+// Validate checks the args against the job type and the upload target
+func (builder *JobBuilder) Validate() error {
+	if err := builder.Type.ValidateArgs(builder.args); err != nil {
+		return err
+	}
+
+	if builder.Repository != nil {
+		if err := builder.Repository.Validate(); err != nil {
+			return err
+		}
+	}
+
+	for _, webhook := range builder.Webhooks {
+		if err := webhook.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if builder.UploadTarget != nil {
+		return builder.UploadTarget.Validate()
+	}
+
+	return builder.UploadType.ValidateArgs(builder.args)
+}
+
+// requestArgs returns the args including the settings
+// of the upload target and the pacman repository
+func (builder *JobBuilder) requestArgs() map[string]string {
+	args := builder.Args()
+
+	if builder.Repository != nil {
+		for k, v := range builder.Repository.Args() {
+			args[k] = v
+		}
+	}
+
+	if builder.UploadTarget != nil {
+		for k, v := range builder.UploadTarget.Args() {
+			args[k] = v
+		}
+	}
+
+	return args
+}
+
+// BuildRequest returns the validated AddJobRequest
+func (builder *JobBuilder) BuildRequest() (*AddJobRequest, error) {
+	if err := builder.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &AddJobRequest{
+		Type:           builder.Type,
+		UploadType:     builder.UploadType,
+		Args:           builder.requestArgs(),
+		DisableCcache:  builder.DisableCcache,
+		IdempotencyKey: builder.IdempotencyKey,
+		Webhooks:       append([]Webhook(nil), builder.Webhooks...),
+	}, nil
+}
+
+// JobRequest implements JobSpec
+func (builder *JobBuilder) JobRequest(ctx context.Context) (*AddJobRequest, error) {
+	return builder.BuildRequest()
+}
+
+// CreateJob creates the job
+func (builder *JobBuilder) CreateJob() (*AddJobResponse, error) {
+	return builder.CreateJobContext(context.Background())
+}
+
+// CreateJobContext creates the job using ctx
+func (builder *JobBuilder) CreateJobContext(ctx context.Context) (*AddJobResponse, error) {
+	request, err := builder.BuildRequest()
+	if err != nil {
+		return nil, err
+	}
+
+	return builder.LibRB.SubmitJobContext(ctx, *request)
+}
@@ -0,0 +1,80 @@
+package libremotebuild
+
+import "context"
+
+// DefaultPageSize page size used by JobIterator if the query has no limit
+const DefaultPageSize = 50
+
+// JobIterator walks lazily through all pages of a job query
+type JobIterator struct {
+	librb LibRB
+	ctx   context.Context
+	query ListJobsRequest
+
+	page []JobInfo
+	pos  int
+	job  JobInfo
+	done bool
+	err  error
+}
+
+// IterateJobs returns an iterator over all jobs matching query.
+// query.Limit is used as page size
+func (librb LibRB) IterateJobs(ctx context.Context, query ListJobsRequest) *JobIterator {
+	if query.Limit <= 0 {
+		query.Limit = DefaultPageSize
+	}
+
+	return &JobIterator{
+		librb: librb,
+		ctx:   ctx,
+		query: query,
+	}
+}
+
+// Next advances to the next job. Returns false if
+// there are no more jobs or an error occurred
+func (iter *JobIterator) Next() bool {
+	for iter.pos >= len(iter.page) {
+		if iter.done || iter.err != nil {
+			return false
+		}
+
+		iter.fetch()
+	}
+
+	iter.job = iter.page[iter.pos]
+	iter.pos++
+	return true
+}
+
+// Job returns the current job
+func (iter *JobIterator) Job() JobInfo {
+	return iter.job
+}
+
+// Err returns the error which stopped the iteration
+func (iter *JobIterator) Err() error {
+	return iter.err
+}
+
+func (iter *JobIterator) fetch() {
+	resp, err := iter.librb.QueryJobsContext(iter.ctx, iter.query)
+	if err != nil {
+		iter.err = err
+		return
+	}
+
+	iter.page = resp.Jobs
+	iter.pos = 0
+
+	// Prefer cursors, fall back to offsets
+	switch {
+	case len(resp.NextCursor) > 0:
+		iter.query.Cursor = resp.NextCursor
+	case len(iter.query.Cursor) > 0, len(resp.Jobs) < iter.query.Limit:
+		iter.done = true
+	default:
+		iter.query.Offset += len(resp.Jobs)
+	}
+}
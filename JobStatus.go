@@ -31,3 +31,14 @@ func (js JobState) String() string {
 
 	return "<invaild>"
 }
+
+// IsTerminal returns true if a job in state js
+// won't change its state anymore
+func (js JobState) IsTerminal() bool {
+	switch js {
+	case JobDone, JobFailed, JobCancelled:
+		return true
+	}
+
+	return false
+}
@@ -1,5 +1,11 @@
 package libremotebuild
 
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
 // JobType type of job
 type JobType uint8
 
@@ -7,14 +13,96 @@ type JobType uint8
 const (
 	JobNoBuild JobType = iota
 	JobAUR
+	JobGit
+	JobLocal
+)
+
+var (
+	// ErrInvalidJobArgs args don't fit the job type
+	ErrInvalidJobArgs = errors.New("invalid job args")
+	// ErrJobTypeRegistered job type or name already registered
+	ErrJobTypeRegistered = errors.New("job type already registered")
 )
 
+// JobTypeInfo describes a registered job type
+type JobTypeInfo struct {
+	Type JobType
+	Name string
+	// RequiredArgs arg keys which must be set
+	RequiredArgs []string
+	// Validate optional validation of the args
+	Validate func(args map[string]string) error
+}
+
+var jobTypes = struct {
+	sync.RWMutex
+	byType map[JobType]JobTypeInfo
+	byName map[string]JobType
+}{
+	byType: make(map[JobType]JobTypeInfo),
+	byName: make(map[string]JobType),
+}
+
+func init() {
+	MustRegisterJobType(JobTypeInfo{
+		Type: JobNoBuild,
+		Name: "NoJob",
+	})
+	MustRegisterJobType(JobTypeInfo{
+		Type:         JobAUR,
+		Name:         "buildAUR",
+		RequiredArgs: []string{AURPackage},
+	})
+	MustRegisterJobType(JobTypeInfo{
+		Type:         JobGit,
+		Name:         "buildGit",
+		RequiredArgs: []string{GitURL},
+		Validate:     validateGitArgs,
+	})
+	MustRegisterJobType(JobTypeInfo{
+		Type:         JobLocal,
+		Name:         "buildLocal",
+		RequiredArgs: []string{LocalUploadID},
+	})
+}
+
+// RegisterJobType registers a new job type. Both
+// the type and its name have to be unique
+func RegisterJobType(info JobTypeInfo) error {
+	jobTypes.Lock()
+	defer jobTypes.Unlock()
+
+	if _, ok := jobTypes.byType[info.Type]; ok {
+		return fmt.Errorf("%w: %d", ErrJobTypeRegistered, info.Type)
+	}
+	if _, ok := jobTypes.byName[info.Name]; ok {
+		return fmt.Errorf("%w: %s", ErrJobTypeRegistered, info.Name)
+	}
+
+	jobTypes.byType[info.Type] = info
+	jobTypes.byName[info.Name] = info.Type
+	return nil
+}
+
+// MustRegisterJobType registers a job type and panics on error
+func MustRegisterJobType(info JobTypeInfo) {
+	if err := RegisterJobType(info); err != nil {
+		panic(err)
+	}
+}
+
+// LookupJobType returns the info of a registered job type
+func LookupJobType(jt JobType) (JobTypeInfo, bool) {
+	jobTypes.RLock()
+	defer jobTypes.RUnlock()
+
+	info, ok := jobTypes.byType[jt]
+	return info, ok
+}
+
 func (jt JobType) String() string {
-	switch jt {
-	case JobNoBuild:
-		return "NoJob"
-	case JobAUR:
-		return "buildAUR"
+	if info, ok := LookupJobType(jt); ok {
+		return info.Name
 	}
 
 	return ""
@@ -22,12 +110,32 @@ func (jt JobType) String() string {
 
 // ParseJobType parse a jobtype from string
 func ParseJobType(inp string) JobType {
-	switch inp {
-	case "NoJob":
-		return JobNoBuild
-	case "buildAUR":
-		return JobAUR
+	jobTypes.RLock()
+	defer jobTypes.RUnlock()
+
+	if jt, ok := jobTypes.byName[inp]; ok {
+		return jt
 	}
 
 	return JobNoBuild
 }
+
+// ValidateArgs checks args against the requirements of the job type
+func (jt JobType) ValidateArgs(args map[string]string) error {
+	info, ok := LookupJobType(jt)
+	if !ok {
+		return fmt.Errorf("%w: unknown job type %d", ErrInvalidJobArgs, jt)
+	}
+
+	for _, key := range info.RequiredArgs {
+		if len(args[key]) == 0 {
+			return fmt.Errorf("%w: %s requires %s", ErrInvalidJobArgs, info.Name, key)
+		}
+	}
+
+	if info.Validate != nil {
+		return info.Validate(args)
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package libremotebuild
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestJobTypeRegistry(t *testing.T) {
+	const jobCustom JobType = 200
+
+	err := RegisterJobType(JobTypeInfo{
+		Type:         jobCustom,
+		Name:         "buildCustom",
+		RequiredArgs: []string{"SRC"},
+		Validate: func(args map[string]string) error {
+			if args["SRC"] == "invalid" {
+				return ErrInvalidJobArgs
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		unregisterJobType(jobCustom)
+	})
+
+	if jobCustom.String() != "buildCustom" || ParseJobType("buildCustom") != jobCustom {
+		t.Fatal("custom job type not resolvable")
+	}
+	if ParseJobType("buildAUR") != JobAUR || JobAUR.String() != "buildAUR" {
+		t.Fatal("builtin job type not resolvable")
+	}
+
+	if err := RegisterJobType(JobTypeInfo{Type: jobCustom, Name: "other"}); !errors.Is(err, ErrJobTypeRegistered) {
+		t.Fatalf("expected duplicate type error, got %v", err)
+	}
+	if err := RegisterJobType(JobTypeInfo{Type: 201, Name: "buildAUR"}); !errors.Is(err, ErrJobTypeRegistered) {
+		t.Fatalf("expected duplicate name error, got %v", err)
+	}
+
+	tests := []struct {
+		args    map[string]string
+		wantErr bool
+	}{
+		{map[string]string{}, true},
+		{map[string]string{"SRC": "invalid"}, true},
+		{map[string]string{"SRC": "valid"}, false},
+	}
+
+	for _, test := range tests {
+		builder := LibRB{}.NewJobBuilder(jobCustom).WithArgs(test.args)
+		if _, err := builder.BuildRequest(); (err != nil) != test.wantErr {
+			t.Errorf("%v: unexpected error %v", test.args, err)
+		}
+	}
+}
+
+// unregisterJobType removes a job type
+func unregisterJobType(jt JobType) {
+	jobTypes.Lock()
+	defer jobTypes.Unlock()
+
+	if info, ok := jobTypes.byType[jt]; ok {
+		delete(jobTypes.byName, info.Name)
+		delete(jobTypes.byType, jt)
+	}
+}
@@ -1,22 +1,50 @@
 package libremotebuild
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"sort"
+	"strconv"
 	"time"
 )
 
 // AddJob a job
 func (librb LibRB) AddJob(jobType JobType, uploadType UploadType, args map[string]string, disableCcache bool) (*AddJobResponse, error) {
-	var response AddJobResponse
+	return librb.AddJobContext(context.Background(), jobType, uploadType, args, disableCcache)
+}
 
-	// Do http request
-	resp, err := librb.NewRequest(EPJobAdd, AddJobRequest{
+// AddJobContext a job using ctx
+func (librb LibRB) AddJobContext(ctx context.Context, jobType JobType, uploadType UploadType, args map[string]string, disableCcache bool) (*AddJobResponse, error) {
+	return librb.SubmitJobContext(ctx, AddJobRequest{
 		Type:          jobType,
 		UploadType:    uploadType,
 		Args:          args,
 		DisableCcache: disableCcache,
-	}).WithAuthFromConfig().
+	})
+}
+
+// SubmitJob creates a job from an AddJobRequest
+func (librb LibRB) SubmitJob(request AddJobRequest) (*AddJobResponse, error) {
+	return librb.SubmitJobContext(context.Background(), request)
+}
+
+// SubmitJobContext creates a job from an AddJobRequest using ctx. If no
+// IdempotencyKey is set a new one gets generated. This allows retrying
+// the request without creating duplicate jobs
+func (librb LibRB) SubmitJobContext(ctx context.Context, request AddJobRequest) (*AddJobResponse, error) {
+	var response AddJobResponse
+
+	if len(request.IdempotencyKey) == 0 {
+		request.IdempotencyKey = NewIdempotencyKey()
+	}
+
+	// Do http request
+	resp, err := librb.NewRequest(EPJobAdd, request).
+		WithContext(ctx).
+		WithAuthFromConfig().
+		WithHeader(HeaderIdempotencyKey, request.IdempotencyKey).
 		WithMethod(PUT).
 		Do(&response)
 
@@ -25,17 +53,35 @@ func (librb LibRB) AddJob(jobType JobType, uploadType UploadType, args map[strin
 		return nil, NewErrorFromResponse(resp, err)
 	}
 
+	response.IdempotencyKey = request.IdempotencyKey
 	return &response, nil
 }
 
+// NewIdempotencyKey generates a new random idempotency key
+func NewIdempotencyKey() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		// Fall back to a time based key
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+
+	return hex.EncodeToString(b)
+}
+
 // ListJobs list running jobs
 func (librb LibRB) ListJobs(limit int) (*ListJobsResponse, error) {
+	return librb.ListJobsContext(context.Background(), limit)
+}
+
+// ListJobsContext list running jobs using ctx
+func (librb LibRB) ListJobsContext(ctx context.Context, limit int) (*ListJobsResponse, error) {
 	var response ListJobsResponse
 
 	// Do http request
 	resp, err := librb.NewRequest(EPJobs, ListJobsRequest{
 		Limit: limit,
-	}).WithAuthFromConfig().
+	}).WithContext(ctx).
+		WithAuthFromConfig().
 		WithMethod(GET).
 		Do(&response)
 
@@ -46,16 +92,48 @@ func (librb LibRB) ListJobs(limit int) (*ListJobsResponse, error) {
 
 	// Sort jobs
 	sort.Sort(SortByJob(response.Jobs))
+	response.redactArgs()
+
+	return &response, nil
+}
 
+// QueryJobs list jobs matching query
+func (librb LibRB) QueryJobs(query ListJobsRequest) (*ListJobsResponse, error) {
+	return librb.QueryJobsContext(context.Background(), query)
+}
+
+// QueryJobsContext list jobs matching query using ctx. Unlike
+// ListJobs, the order returned by the server is kept
+func (librb LibRB) QueryJobsContext(ctx context.Context, query ListJobsRequest) (*ListJobsResponse, error) {
+	var response ListJobsResponse
+
+	// Do http request
+	resp, err := librb.NewRequest(EPJobs, query).
+		WithContext(ctx).
+		WithAuthFromConfig().
+		WithMethod(GET).
+		Do(&response)
+
+	// Return new error on ... error
+	if err != nil || resp.Status == ResponseError {
+		return nil, NewErrorFromResponse(resp, err)
+	}
+
+	response.redactArgs()
 	return &response, nil
 }
 
 // SetJobState pauses a running or queued job
 func (librb LibRB) SetJobState(jobID uint, state JobState) error {
+	return librb.SetJobStateContext(context.Background(), jobID, state)
+}
+
+// SetJobStateContext pauses a running or queued job using ctx
+func (librb LibRB) SetJobStateContext(ctx context.Context, jobID uint, state JobState) error {
 	switch state {
 	case JobPaused, JobRunning:
 	default:
-		return fmt.Errorf("Invalid state to set job to")
+		return fmt.Errorf("%w: can't set job to %s", ErrInvalidState, state)
 	}
 
 	endpoint := EPJobPause
@@ -66,7 +144,8 @@ func (librb LibRB) SetJobState(jobID uint, state JobState) error {
 	// Do http request
 	resp, err := librb.NewRequest(endpoint, JobRequest{
 		JobID: jobID,
-	}).WithAuthFromConfig().
+	}).WithContext(ctx).
+		WithAuthFromConfig().
 		WithMethod(PUT).
 		Do(nil)
 
@@ -80,10 +159,16 @@ func (librb LibRB) SetJobState(jobID uint, state JobState) error {
 
 // CancelJob cancel a running or queued job
 func (librb LibRB) CancelJob(jobID uint) error {
+	return librb.CancelJobContext(context.Background(), jobID)
+}
+
+// CancelJobContext cancel a running or queued job using ctx
+func (librb LibRB) CancelJobContext(ctx context.Context, jobID uint) error {
 	// Do http request
 	resp, err := librb.NewRequest(EPJobCancel, JobRequest{
 		JobID: jobID,
-	}).WithAuthFromConfig().
+	}).WithContext(ctx).
+		WithAuthFromConfig().
 		WithMethod(POST).
 		Do(nil)
 
@@ -97,12 +182,18 @@ func (librb LibRB) CancelJob(jobID uint) error {
 
 // JobInfo gets information for a job
 func (librb LibRB) JobInfo(jobID uint) (*JobInfo, error) {
+	return librb.JobInfoContext(context.Background(), jobID)
+}
+
+// JobInfoContext gets information for a job using ctx
+func (librb LibRB) JobInfoContext(ctx context.Context, jobID uint) (*JobInfo, error) {
 	var response JobInfo
 
 	// Do http request
 	resp, err := librb.NewRequest(EPJobInfo, JobRequest{
 		JobID: jobID,
-	}).WithAuthFromConfig().
+	}).WithContext(ctx).
+		WithAuthFromConfig().
 		WithMethod(GET).
 		Do(&response)
 
@@ -111,16 +202,25 @@ func (librb LibRB) JobInfo(jobID uint) (*JobInfo, error) {
 		return nil, NewErrorFromResponse(resp, err)
 	}
 
+	// Don't rely on the server hiding secrets
+	response.Args = RedactArgs(response.Args)
 	return &response, nil
 }
 
 // Logs for a job
 func (librb LibRB) Logs(jobID uint, since time.Time) (*RestRequestResponse, error) {
+	return librb.LogsContext(context.Background(), jobID, since)
+}
+
+// LogsContext for a job using ctx. The body of the returned
+// response stays bound to ctx, cancelling it stops the stream
+func (librb LibRB) LogsContext(ctx context.Context, jobID uint, since time.Time) (*RestRequestResponse, error) {
 	// Do http request
 	resp, err := librb.NewRequest(EPJobLogs, JobLogsRequest{
 		Since: since,
 		JobID: jobID,
-	}).WithAuthFromConfig().
+	}).WithContext(ctx).
+		WithAuthFromConfig().
 		WithNoBodyClose().
 		WithMethod(GET).
 		Do(nil)
@@ -0,0 +1,254 @@
+package libremotebuild
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+)
+
+// ErrNoPKGBUILD directory doesn't contain a PKGBUILD
+var ErrNoPKGBUILD = errors.New("no PKGBUILD found")
+
+// ProgressFunc gets called with the amount of bytes uploaded so far
+type ProgressFunc func(uploaded int64)
+
+// LocalBuild build a PKGBUILD from a local directory. The directory
+// gets uploaded as tar.gz archive before the job is created
+type LocalBuild struct {
+	JobBuilder
+	Dir string
+
+	ignore   ignoreMatcher
+	progress ProgressFunc
+}
+
+// NewLocalBuild build the PKGBUILD located in dir. Files matching
+// the patterns of dir/.gitignore are not uploaded
+func (librb LibRB) NewLocalBuild(dir string) *LocalBuild {
+	localBuild := &LocalBuild{
+		JobBuilder: *librb.NewJobBuilder(JobLocal),
+		Dir:        dir,
+	}
+
+	localBuild.ignore.add(".git/")
+	return localBuild
+}
+
+// WithExcludes excludes files matching .gitignore style patterns
+func (localBuild *LocalBuild) WithExcludes(patterns ...string) *LocalBuild {
+	for _, pattern := range patterns {
+		localBuild.ignore.add(pattern)
+	}
+
+	return localBuild
+}
+
+// WithProgress reports the upload progress to fn
+func (localBuild *LocalBuild) WithProgress(fn ProgressFunc) *LocalBuild {
+	localBuild.progress = fn
+	return localBuild
+}
+
+// WithoutCcache disables ccache
+func (localBuild *LocalBuild) WithoutCcache() *LocalBuild {
+	localBuild.JobBuilder.WithoutCcache()
+	return localBuild
+}
+
+// WithIdempotencyKey use key for creating the job
+func (localBuild *LocalBuild) WithIdempotencyKey(key string) *LocalBuild {
+	localBuild.JobBuilder.WithIdempotencyKey(key)
+	return localBuild
+}
+
+// WithUploadTarget upload the result to target
+func (localBuild *LocalBuild) WithUploadTarget(target UploadTarget) *LocalBuild {
+	localBuild.JobBuilder.WithUploadTarget(target)
+	return localBuild
+}
+
+// WithPacmanRepo add the built packages to repo after the build
+func (localBuild *LocalBuild) WithPacmanRepo(repo PacmanRepo) *LocalBuild {
+	localBuild.JobBuilder.WithPacmanRepo(repo)
+	return localBuild
+}
+
+// WithWebhook notify webhookURL about events of the job
+func (localBuild *LocalBuild) WithWebhook(webhookURL, secret string, events ...JobEventType) *LocalBuild {
+	localBuild.JobBuilder.WithWebhook(webhookURL, secret, events...)
+	return localBuild
+}
+
+// CreateJob uploads the sources and creates the job
+func (localBuild *LocalBuild) CreateJob() (*AddJobResponse, error) {
+	return localBuild.CreateJobContext(context.Background())
+}
+
+// CreateJobContext uploads the sources and creates the job using ctx
+func (localBuild *LocalBuild) CreateJobContext(ctx context.Context) (*AddJobResponse, error) {
+	request, err := localBuild.JobRequest(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return localBuild.LibRB.SubmitJobContext(ctx, *request)
+}
+
+// JobRequest implements JobSpec. Uploads the sources
+func (localBuild *LocalBuild) JobRequest(ctx context.Context) (*AddJobRequest, error) {
+	upload, err := localBuild.Upload(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	localBuild.WithArg(LocalUploadID, upload.ID)
+	return localBuild.BuildRequest()
+}
+
+// Upload uploads the directory without creating a job
+func (localBuild *LocalBuild) Upload(ctx context.Context) (*UploadResponse, error) {
+	if _, err := os.Stat(filepath.Join(localBuild.Dir, "PKGBUILD")); err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("%w in %s", ErrNoPKGBUILD, localBuild.Dir)
+		}
+		return nil, err
+	}
+
+	// Patterns added by WithExcludes take precedence
+	var matcher ignoreMatcher
+	if err := matcher.readIgnoreFile(filepath.Join(localBuild.Dir, ".gitignore")); err != nil {
+		return nil, err
+	}
+	matcher.patterns = append(matcher.patterns, localBuild.ignore.patterns...)
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(writeTarGz(pw, localBuild.Dir, &matcher))
+	}()
+	defer pr.Close()
+
+	var reader io.Reader = pr
+	if localBuild.progress != nil {
+		reader = &progressReader{
+			reader:   pr,
+			progress: localBuild.progress,
+		}
+	}
+
+	return localBuild.LibRB.UploadSourcesContext(ctx, reader)
+}
+
+// UploadSources uploads a tar.gz archive containing a PKGBUILD
+func (librb LibRB) UploadSources(archive io.Reader) (*UploadResponse, error) {
+	return librb.UploadSourcesContext(context.Background(), archive)
+}
+
+// UploadSourcesContext uploads a tar.gz archive containing a PKGBUILD using ctx
+func (librb LibRB) UploadSourcesContext(ctx context.Context, archive io.Reader) (*UploadResponse, error) {
+	var response UploadResponse
+
+	// Do http request
+	resp, err := librb.NewRequest(EPJobUpload, archive).
+		WithContext(ctx).
+		WithAuthFromConfig().
+		WithMethod(PUT).
+		WithRequestType(RawRequestType).
+		WithContentType(TarGzContentType).
+		Do(&response)
+
+	// Return new error on ... error
+	if err != nil || resp.Status == ResponseError {
+		return nil, NewErrorFromResponse(resp, err)
+	}
+
+	return &response, nil
+}
+
+// writeTarGz writes all files of dir not ignored by matcher into w
+func writeTarGz(w io.Writer, dir string, matcher *ignoreMatcher) error {
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+
+	err := filepath.Walk(dir, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, file)
+		if err != nil || rel == "." {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if matcher.match(rel, info.IsDir()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		var link string
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(file); err != nil {
+				return err
+			}
+		}
+
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		header.Name = rel
+		if info.IsDir() {
+			header.Name += "/"
+		}
+
+		if err = tw.WriteHeader(header); err != nil {
+			return err
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if err = tw.Close(); err != nil {
+		return err
+	}
+
+	return gw.Close()
+}
+
+// progressReader reports the amount of bytes read
+type progressReader struct {
+	reader   io.Reader
+	progress ProgressFunc
+	read     int64
+}
+
+func (pr *progressReader) Read(p []byte) (int, error) {
+	n, err := pr.reader.Read(p)
+	if n > 0 {
+		pr.progress(atomic.AddInt64(&pr.read, int64(n)))
+	}
+
+	return n, err
+}
@@ -0,0 +1,232 @@
+package libremotebuild
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Reconnect delays used by LogFollower
+const (
+	DefaultLogReconnectDelay    = 1 * time.Second
+	DefaultLogMaxReconnectDelay = 30 * time.Second
+)
+
+// LogLine a single line of a job log
+type LogLine struct {
+	JobID uint
+	// Time the line was received by the client. The log
+	// stream doesn't carry the time a line was logged at
+	Time time.Time
+	Text string
+}
+
+// LogFollower follows the logs of a job until it reaches
+// a terminal state. Dropped connections are re-established
+// using the original since, lines which were already
+// received are skipped
+type LogFollower struct {
+	librb  LibRB
+	jobID  uint
+	since  time.Time
+	offset uint
+
+	reconnectDelay    time.Duration
+	maxReconnectDelay time.Duration
+
+	lines  chan LogLine
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mx  sync.Mutex
+	err error
+}
+
+// FollowLogs starts following the logs of a job. All lines newer
+// than since are sent to LogFollower.Lines(). The returned follower
+// has to be closed using Close() if not read until the end
+func (librb LibRB) FollowLogs(ctx context.Context, jobID uint, since time.Time) *LogFollower {
+	ctx, cancel := context.WithCancel(ctx)
+
+	follower := &LogFollower{
+		librb:             librb,
+		jobID:             jobID,
+		since:             since,
+		reconnectDelay:    DefaultLogReconnectDelay,
+		maxReconnectDelay: DefaultLogMaxReconnectDelay,
+		lines:             make(chan LogLine),
+		cancel:            cancel,
+		done:              make(chan struct{}),
+	}
+
+	go follower.run(ctx)
+	return follower
+}
+
+// Lines returns the channel receiving the log lines. It gets
+// closed once the job is finished or following failed
+func (follower *LogFollower) Lines() <-chan LogLine {
+	return follower.lines
+}
+
+// Err returns the error which stopped the follower. Returns nil if
+// the job finished regularly. Only valid after Lines() was closed
+func (follower *LogFollower) Err() error {
+	follower.mx.Lock()
+	defer follower.mx.Unlock()
+	return follower.err
+}
+
+// Since returns the since value the follower was started with
+func (follower *LogFollower) Since() time.Time {
+	return follower.since
+}
+
+// Offset returns the amount of lines received so far
+func (follower *LogFollower) Offset() uint {
+	follower.mx.Lock()
+	defer follower.mx.Unlock()
+	return follower.offset
+}
+
+// Close stops following and waits for the follower to exit
+func (follower *LogFollower) Close() error {
+	follower.cancel()
+	<-follower.done
+
+	err := follower.Err()
+	if err == context.Canceled {
+		return nil
+	}
+
+	return err
+}
+
+func (follower *LogFollower) run(ctx context.Context) {
+	defer close(follower.done)
+	defer close(follower.lines)
+
+	delay := follower.reconnectDelay
+	final := false
+
+	for {
+		received, streamErr := follower.stream(ctx)
+		if ctx.Err() != nil {
+			follower.setErr(ctx.Err())
+			return
+		}
+
+		// Server rejected the request eg. job not found
+		if isServerError(streamErr) || final {
+			follower.setErr(streamErr)
+			return
+		}
+
+		info, err := follower.librb.JobInfoContext(ctx, follower.jobID)
+		if err != nil {
+			if isServerError(err) {
+				follower.setErr(err)
+				return
+			}
+		} else if info.Status.IsTerminal() {
+			// Stream ended regularly after the job
+			// was done. Nothing left to read
+			if streamErr == nil {
+				return
+			}
+
+			// Fetch remaining lines once more
+			final = true
+			continue
+		}
+
+		if received {
+			delay = follower.reconnectDelay
+		}
+
+		select {
+		case <-ctx.Done():
+			follower.setErr(ctx.Err())
+			return
+		case <-time.After(delay):
+		}
+
+		if delay *= 2; delay > follower.maxReconnectDelay {
+			delay = follower.maxReconnectDelay
+		}
+	}
+}
+
+// stream reads the log stream once. Returns true if
+// at least one line was received
+func (follower *LogFollower) stream(ctx context.Context) (bool, error) {
+	resp, err := follower.librb.LogsContext(ctx, follower.jobID, follower.since)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Response.Body.Close()
+
+	var received bool
+	// The server resends all lines newer than since
+	skip := follower.Offset()
+	reader := bufio.NewReader(resp.Response.Body)
+
+	for {
+		line, err := reader.ReadString('\n')
+
+		// Drop incomplete lines on errors. The offset
+		// isn't increased, so they get resent after
+		// reconnecting
+		if err != nil && (err != io.EOF || len(line) == 0) {
+			if err == io.EOF {
+				err = nil
+			}
+			return received, err
+		}
+
+		if skip > 0 {
+			skip--
+			if err == io.EOF {
+				return received, nil
+			}
+			continue
+		}
+
+		logLine := LogLine{
+			JobID: follower.jobID,
+			Time:  time.Now(),
+			Text:  strings.TrimRight(line, "\r\n"),
+		}
+
+		select {
+		case <-ctx.Done():
+			return received, ctx.Err()
+		case follower.lines <- logLine:
+		}
+
+		received = true
+		follower.mx.Lock()
+		follower.offset++
+		follower.mx.Unlock()
+
+		if err == io.EOF {
+			return received, nil
+		}
+	}
+}
+
+func (follower *LogFollower) setErr(err error) {
+	follower.mx.Lock()
+	follower.err = err
+	follower.mx.Unlock()
+}
+
+// isServerError returns true if err was
+// returned by the server itself
+func isServerError(err error) bool {
+	return errors.Is(err, ErrResponseError)
+}
@@ -0,0 +1,116 @@
+package libremotebuild
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Handler sends a single http request
+type Handler func(req *http.Request) (*http.Response, error)
+
+// Middleware wraps a Handler to observe or modify requests and responses
+type Middleware func(next Handler) Handler
+
+// LogFunc logs msg with alternating key value pairs
+type LogFunc func(msg string, keyvals ...interface{})
+
+// Use adds middlewares to all requests. The first
+// middleware is the outermost one
+func (librb *LibRB) Use(mw ...Middleware) *LibRB {
+	librb.Middlewares = append(librb.Middlewares, mw...)
+	return librb
+}
+
+// WithMiddleware adds middlewares to the request
+func (request *Request) WithMiddleware(mw ...Middleware) *Request {
+	// Don't write into the slice shared with LibRB
+	request.Middlewares = append(request.Middlewares[:len(request.Middlewares):len(request.Middlewares)], mw...)
+	return request
+}
+
+// buildHandler wraps client.Do into all middlewares
+func (request *Request) buildHandler(client *http.Client) Handler {
+	handler := Handler(client.Do)
+
+	for i := len(request.Middlewares) - 1; i >= 0; i-- {
+		handler = request.Middlewares[i](handler)
+	}
+
+	return handler
+}
+
+// HeaderMiddleware sets headers on each request
+func HeaderMiddleware(headers map[string]string) Middleware {
+	return func(next Handler) Handler {
+		return func(req *http.Request) (*http.Response, error) {
+			for k, v := range headers {
+				req.Header.Set(k, v)
+			}
+
+			return next(req)
+		}
+	}
+}
+
+// LoggingMiddleware logs each request attempt with method, url, status,
+// duration and request headers. The Authorization header is redacted
+func LoggingMiddleware(logf LogFunc) Middleware {
+	return func(next Handler) Handler {
+		return func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next(req)
+
+			keyvals := []interface{}{
+				"method", req.Method,
+				"url", req.URL.String(),
+				"duration", time.Since(start),
+				"headers", RedactHeaders(req.Header),
+			}
+
+			if err != nil {
+				logf("request failed", append(keyvals, "error", err)...)
+				return resp, err
+			}
+
+			keyvals = append(keyvals,
+				"status", resp.StatusCode,
+				"responseStatus", resp.Header.Get(HeaderStatus),
+				"responseMessage", resp.Header.Get(HeaderStatusMessage),
+			)
+
+			logf("request done", keyvals...)
+			return resp, err
+		}
+	}
+}
+
+// StdLogFunc returns a LogFunc writing 'msg key=value ...' lines to logger
+func StdLogFunc(logger *log.Logger) LogFunc {
+	return func(msg string, keyvals ...interface{}) {
+		var sb strings.Builder
+		sb.WriteString(msg)
+
+		for i := 0; i+1 < len(keyvals); i += 2 {
+			fmt.Fprintf(&sb, " %v=%q", keyvals[i], fmt.Sprint(keyvals[i+1]))
+		}
+
+		logger.Println(sb.String())
+	}
+}
+
+// RedactHeaders returns a copy of h with
+// credentials replaced by "<redacted>"
+func RedactHeaders(h http.Header) http.Header {
+	redacted := h.Clone()
+
+	for _, key := range []string{"Authorization", "Proxy-Authorization", "Cookie"} {
+		if len(redacted.Get(key)) > 0 {
+			redacted.Set(key, "<redacted>")
+		}
+	}
+
+	return redacted
+}
@@ -0,0 +1,31 @@
+package libremotebuild
+
+import "strings"
+
+// Package a package built by a job
+type Package struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Arch    string `json:"arch"`
+}
+
+// ParsePackageFile parses a package file named
+// <pkgname>-<pkgver>-<pkgrel>-<arch>.pkg.tar.*
+func ParsePackageFile(file string) (Package, bool) {
+	i := strings.Index(file, ".pkg.tar")
+	if i < 0 {
+		return Package{}, false
+	}
+
+	parts := strings.Split(file[:i], "-")
+	if len(parts) < 4 {
+		return Package{}, false
+	}
+
+	n := len(parts)
+	return Package{
+		Name:    strings.Join(parts[:n-3], "-"),
+		Version: parts[n-3] + "-" + parts[n-2],
+		Arch:    parts[n-1],
+	}, true
+}
@@ -0,0 +1,23 @@
+package libremotebuild
+
+import "testing"
+
+func TestParsePackageFile(t *testing.T) {
+	tests := []struct {
+		file     string
+		expected Package
+		ok       bool
+	}{
+		{"yay-12.1.0-1-x86_64.pkg.tar.zst", Package{"yay", "12.1.0-1", "x86_64"}, true},
+		{"python-foo-bar-1:2.0-3-any.pkg.tar.xz", Package{"python-foo-bar", "1:2.0-3", "any"}, true},
+		{"yay-1-x86_64.pkg.tar.zst", Package{}, false},
+		{"build.log", Package{}, false},
+	}
+
+	for _, test := range tests {
+		pkg, ok := ParsePackageFile(test.file)
+		if ok != test.ok || pkg != test.expected {
+			t.Errorf("%s: unexpected %+v %v", test.file, pkg, ok)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package libremotebuild
+
+import (
+	"fmt"
+	"regexp"
+)
+
+var (
+	repoNameRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+	signKeyRegex  = regexp.MustCompile(`^(0x)?[0-9a-fA-F]{8,40}$`)
+)
+
+// PacmanRepo pacman repository the built packages get added to
+// after a successful build, like running repo-add on the server
+type PacmanRepo struct {
+	// Name of the repository database
+	Name string
+	// Sign sign the database
+	Sign bool
+	// SignKey key used for signing. Uses the servers default key if empty
+	SignKey string
+	// RemoveOld remove superseded versions of the packages
+	RemoveOld bool
+}
+
+// RepositoryState result of publishing to a pacman repository
+type RepositoryState struct {
+	Name      string `json:"name"`
+	Published bool   `json:"published"`
+	Signed    bool   `json:"signed"`
+	// Added package files added to the database
+	Added []string `json:"added,omitempty"`
+	// Removed superseded package files
+	Removed []string `json:"removed,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// PacmanRepoFromArgs returns the repository set in args or nil
+func PacmanRepoFromArgs(args map[string]string) *PacmanRepo {
+	if _, ok := args[PacmanRepoName]; !ok {
+		return nil
+	}
+
+	return &PacmanRepo{
+		Name:      args[PacmanRepoName],
+		Sign:      args[PacmanRepoSign] == "true",
+		SignKey:   args[PacmanRepoKey],
+		RemoveOld: args[PacmanRepoRemoveOld] == "true",
+	}
+}
+
+// Args returns the repository settings as job args
+func (repo PacmanRepo) Args() map[string]string {
+	args := map[string]string{
+		PacmanRepoName: repo.Name,
+	}
+
+	if repo.Sign {
+		args[PacmanRepoSign] = "true"
+	}
+	if len(repo.SignKey) > 0 {
+		args[PacmanRepoKey] = repo.SignKey
+	}
+	if repo.RemoveOld {
+		args[PacmanRepoRemoveOld] = "true"
+	}
+
+	return args
+}
+
+// Validate checks the repository settings
+func (repo PacmanRepo) Validate() error {
+	if !repoNameRegex.MatchString(repo.Name) {
+		return fmt.Errorf("%w: invalid repository name %q", ErrInvalidJobArgs, repo.Name)
+	}
+
+	if len(repo.SignKey) > 0 {
+		if !repo.Sign {
+			return fmt.Errorf("%w: sign key set without signing", ErrInvalidJobArgs)
+		}
+		if !signKeyRegex.MatchString(repo.SignKey) {
+			return fmt.Errorf("%w: invalid sign key %q", ErrInvalidJobArgs, repo.SignKey)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package libremotebuild
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Replacements of secrets. Passwords of urls use a different value
+// as < and > would be percent-encoded by url.URL.String
+const (
+	redacted         = "<redacted>"
+	redactedPassword = "xxxxx"
+)
+
+// secretArgs arg keys holding secrets
+var secretArgs = map[string]bool{
+	DMToken:     true,
+	S3AccessKey: true,
+	S3SecretKey: true,
+}
+
+// urlArgs arg keys holding urls which may contain a password
+var urlArgs = []string{GitURL, S3Endpoint}
+
+// IsSecretArg returns true if the value of the arg key is a secret
+func IsSecretArg(key string) bool {
+	if secretArgs[key] {
+		return true
+	}
+
+	key = strings.ToUpper(key)
+	for _, s := range []string{"TOKEN", "PASSWORD", "SECRET_KEY", "PRIVATE"} {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RedactArgs returns a copy of args with secrets replaced
+// by "<redacted>" and passwords of urls by "xxxxx"
+func RedactArgs(args map[string]string) map[string]string {
+	if args == nil {
+		return nil
+	}
+
+	c := make(map[string]string, len(args))
+	for k, v := range args {
+		if IsSecretArg(k) && len(v) > 0 {
+			v = redacted
+		}
+
+		c[k] = v
+	}
+
+	for _, key := range urlArgs {
+		u, err := url.Parse(c[key])
+		if err != nil || u.User == nil {
+			continue
+		}
+
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), redactedPassword)
+			c[key] = u.String()
+		}
+	}
+
+	return c
+}
+
+// redactArgs redacts the args of all jobs
+func (response *ListJobsResponse) redactArgs() {
+	for i := range response.Jobs {
+		response.Jobs[i].Args = RedactArgs(response.Jobs[i].Args)
+	}
+}
@@ -2,6 +2,7 @@ package libremotebuild
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"encoding/json"
 	"fmt"
@@ -11,6 +12,8 @@ import (
 	"net/url"
 	"path"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -30,7 +33,8 @@ type ContentType string
 
 // Content types
 const (
-	JSONContentType ContentType = "application/json"
+	JSONContentType  ContentType = "application/json"
+	TarGzContentType ContentType = "application/gzip"
 )
 
 // PingRequest a ping request content
@@ -58,6 +62,11 @@ const (
 	EPJobCancel          = EPJob + "/cancel"
 	EPJobInfo            = EPJob + "/info"
 	EPJobs               = EPJob + "s"
+	EPJobUpload          = EPJob + "/upload"
+	EPJobEvents          = EPJob + "/events"
+
+	EPJobArtifacts        = EPJob + "/artifacts"
+	EPJobArtifactDownload = EPJobArtifacts + "/download"
 
 	EPJobState  = EPJob + "/state"
 	EPJobPause  = EPJobState + "/pause"
@@ -71,21 +80,40 @@ const (
 
 // RequestConfig configurations for requests
 type RequestConfig struct {
-	IgnoreCert   bool
-	URL          string
-	MachineID    string
-	Username     string
+	IgnoreCert bool
+	URL        string
+	MachineID  string
+	Username   string
+	// SessionToken use Get/SetSessionToken if
+	// the config is shared across goroutines
 	SessionToken string
+
+	mx        sync.RWMutex
+	refreshMx sync.Mutex
 }
 
 // GetBearerAuth returns bearer authorization from config
-func (rc RequestConfig) GetBearerAuth() Authorization {
+func (rc *RequestConfig) GetBearerAuth() Authorization {
 	return Authorization{
 		Type:    Bearer,
-		Palyoad: rc.SessionToken,
+		Palyoad: rc.GetSessionToken(),
 	}
 }
 
+// GetSessionToken returns the session token
+func (rc *RequestConfig) GetSessionToken() string {
+	rc.mx.RLock()
+	defer rc.mx.RUnlock()
+	return rc.SessionToken
+}
+
+// SetSessionToken sets the session token
+func (rc *RequestConfig) SetSessionToken(token string) {
+	rc.mx.Lock()
+	rc.SessionToken = token
+	rc.mx.Unlock()
+}
+
 // Request a rest server request
 type Request struct {
 	RequestType   RequestType
@@ -98,6 +126,14 @@ type Request struct {
 	Headers       map[string]string
 	BenchChan     chan time.Time
 	CloseBody     bool
+	Context       context.Context
+	Client        *http.Client
+	RetryPolicy   RetryPolicy
+	AuthProvider  AuthProvider
+	Middlewares   []Middleware
+
+	defaultAuth AuthProvider
+	sentAuth    *Authorization
 }
 
 // CredentialsRequest request containing credentials
@@ -113,6 +149,13 @@ type AddJobRequest struct {
 	Args          map[string]string `json:"args"`
 	UploadType    UploadType        `json:"uploadtype"`
 	DisableCcache bool              `json:"disableccache"`
+	// IdempotencyKey client generated key. Sending the same key
+	// twice returns the already created job instead of a new one
+	IdempotencyKey string `json:"idempotencykey,omitempty"`
+	// DependsOn jobs which have to be done before this job starts
+	DependsOn []uint `json:"dependson,omitempty"`
+	// Webhooks notified about events of the job
+	Webhooks []Webhook `json:"webhooks,omitempty"`
 }
 
 // JobRequest cancel a job
@@ -126,11 +169,51 @@ type JobLogsRequest struct {
 	Since time.Time `json:"since"`
 }
 
-// ListJobsRequest request for listing jobs
+// JobEventsRequest subscribe to job events. Empty
+// filters match all jobs or event types
+type JobEventsRequest struct {
+	JobIDs []uint         `json:"ids,omitempty"`
+	Types  []JobEventType `json:"types,omitempty"`
+}
+
+// ArtifactRequest request for a single artifact of a job
+type ArtifactRequest struct {
+	JobID uint   `json:"id"`
+	Name  string `json:"name"`
+}
+
+// ListJobsRequest request for listing jobs. If SortBy
+// is empty, jobs are returned newest first
 type ListJobsRequest struct {
 	Limit int `json:"l"`
+
+	// Pagination. Cursor takes precedence over Offset
+	Offset int    `json:"o,omitempty"`
+	Cursor string `json:"c,omitempty"`
+
+	// Filters
+	States      []JobState   `json:"states,omitempty"`
+	Types       []JobType    `json:"types,omitempty"`
+	UploadTypes []UploadType `json:"uploadtypes,omitempty"`
+	Since       *time.Time   `json:"since,omitempty"`
+	Until       *time.Time   `json:"until,omitempty"`
+	Package     string       `json:"pkg,omitempty"`
+
+	SortBy     JobSortKey `json:"sort,omitempty"`
+	Descending bool       `json:"desc,omitempty"`
 }
 
+// JobSortKey key to sort jobs by
+type JobSortKey string
+
+// Sort keys
+const (
+	SortByID       JobSortKey = "id"
+	SortByCreated  JobSortKey = "created"
+	SortByState    JobSortKey = "state"
+	SortByDuration JobSortKey = "duration"
+)
+
 // RequestType type of request
 type RequestType uint8
 
@@ -150,6 +233,10 @@ func (limdm *LibRB) NewRequest(endpoint Endpoint, payload interface{}) *Request
 		Method:      POST,
 		ContentType: JSONContentType,
 		CloseBody:   true,
+		Client:      limdm.Client,
+		RetryPolicy: limdm.RetryPolicy,
+		Middlewares: limdm.Middlewares,
+		defaultAuth: limdm.defaultAuth(),
 	}
 }
 
@@ -174,13 +261,35 @@ func (request *Request) WithRequestType(rType RequestType) *Request {
 // WithAuth with authorization
 func (request *Request) WithAuth(a Authorization) *Request {
 	request.Authorization = &a
+	request.AuthProvider = nil
 	return request
 }
 
-// WithAuthFromConfig with authorization
+// WithAuthProvider consult p for the authorization of each attempt
+func (request *Request) WithAuthProvider(p AuthProvider) *Request {
+	request.AuthProvider = p
+	return request
+}
+
+// WithAuthFromConfig with authorization. Uses the AuthProvider of LibRB or
+// the session token of the config. If the authorization gets rejected and
+// the provider is refreshable, it gets refreshed and the request is replayed once
 func (request *Request) WithAuthFromConfig() *Request {
-	auth := request.Config.GetBearerAuth()
-	request.Authorization = &auth
+	if request.defaultAuth != nil {
+		request.AuthProvider = request.defaultAuth
+	} else {
+		request.AuthProvider = &SessionAuth{
+			LibRB: LibRB{Config: request.Config},
+		}
+	}
+
+	return request
+}
+
+// WithContext use ctx for the request. Cancelling ctx aborts the
+// request and, if the body is kept open, reading the body
+func (request *Request) WithContext(ctx context.Context) *Request {
+	request.Context = ctx
 	return request
 }
 
@@ -206,8 +315,25 @@ func (request *Request) WithHeader(name string, value string) *Request {
 	return request
 }
 
-// BuildClient return client
+// WithClient use client for the request
+func (request *Request) WithClient(client *http.Client) *Request {
+	request.Client = client
+	return request
+}
+
+// WithRetryPolicy use policy to retry failed attempts. Pass nil to disable retries
+func (request *Request) WithRetryPolicy(policy RetryPolicy) *Request {
+	request.RetryPolicy = policy
+	return request
+}
+
+// BuildClient return client. Uses the shared client of
+// the request if set, otherwise a new one gets created
 func (request *Request) BuildClient() *http.Client {
+	if request.Client != nil {
+		return request.Client
+	}
+
 	return &http.Client{
 		Transport: &http.Transport{
 			TLSClientConfig: &tls.Config{
@@ -218,10 +344,45 @@ func (request *Request) BuildClient() *http.Client {
 	}
 }
 
-// DoHTTPRequest do plain http request
+// DoHTTPRequest do plain http request. Failed attempts are
+// retried according to the retry policy of the request
 func (request *Request) DoHTTPRequest() (*http.Response, error) {
-	client := request.BuildClient()
+	handler := request.buildHandler(request.BuildClient())
+	ctx := request.getContext()
+
+	for attempt := 1; ; attempt++ {
+		req, err := request.buildHTTPRequest(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := handler(req)
+		if request.RetryPolicy == nil || !request.isRetryable() || ctx.Err() != nil {
+			return resp, err
+		}
+
+		delay, retry := request.RetryPolicy.Retry(attempt, resp, err)
+		if !retry {
+			return resp, err
+		}
+
+		// Discard response of failed attempt
+		if resp != nil {
+			closeBody(resp)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
 
+// buildHTTPRequest creates the http.Request for one attempt
+func (request *Request) buildHTTPRequest(ctx context.Context) (*http.Request, error) {
 	// Build url
 	u, err := url.Parse(request.Config.URL)
 	if err != nil {
@@ -247,8 +408,6 @@ func (request *Request) DoHTTPRequest() (*http.Response, error) {
 			reader = bytes.NewReader((request.Payload).([]byte))
 		case io.Reader:
 			reader = (request.Payload).(io.Reader)
-		case io.PipeReader:
-			reader = (request.Payload).(*io.PipeReader)
 		}
 	}
 
@@ -257,7 +416,10 @@ func (request *Request) DoHTTPRequest() (*http.Response, error) {
 	}
 
 	// Bulid request
-	req, _ := http.NewRequest(string(request.Method), u.String(), reader)
+	req, err := http.NewRequestWithContext(ctx, string(request.Method), u.String(), reader)
+	if err != nil {
+		return nil, err
+	}
 
 	// Set contenttype header
 	req.Header.Set("Content-Type", string(request.ContentType))
@@ -266,44 +428,91 @@ func (request *Request) DoHTTPRequest() (*http.Response, error) {
 		req.Header.Set(headerKey, headerValue)
 	}
 
+	// Get authorization for this attempt
+	auth := request.Authorization
+	if request.AuthProvider != nil {
+		if auth, err = request.AuthProvider.Authorization(ctx); err != nil {
+			return nil, err
+		}
+	}
+	request.sentAuth = auth
+
 	// Set Authorization header
-	if request.Authorization != nil {
-		req.Header.Set("Authorization", fmt.Sprintf("%s %s", string(request.Authorization.Type), request.Authorization.Palyoad))
+	if auth != nil {
+		req.Header.Set("Authorization", fmt.Sprintf("%s %s", string(auth.Type), auth.Palyoad))
+	}
+
+	return req, nil
+}
+
+func (request *Request) getContext() context.Context {
+	if request.Context == nil {
+		return context.Background()
 	}
 
-	return client.Do(req)
+	return request.Context
+}
+
+// isReplayable returns true if the payload can be sent twice
+func (request *Request) isReplayable() bool {
+	if request.RequestType == RawRequestType {
+		if _, ok := request.Payload.([]byte); !ok && request.Payload != nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isRetryable returns true if the request can be sent
+// multiple times without causing side effects
+func (request *Request) isRetryable() bool {
+	if !request.isReplayable() {
+		return false
+	}
+
+	if request.Method == GET {
+		return true
+	}
+
+	_, hasKey := request.Headers[HeaderIdempotencyKey]
+	return hasKey
 }
 
 // Do a better request method
 func (request Request) Do(retVar interface{}) (*RestRequestResponse, error) {
 	resp, err := request.DoHTTPRequest()
 
-	// Call bench callbac
+	// Replay request once using a refreshed session
+	if err == nil && resp.StatusCode == http.StatusUnauthorized && request.canRefresh() {
+		resp, err = request.refreshAndReplay(resp)
+	}
+
+	// Call bench callback. Don't block if nobody is reading
 	if request.BenchChan != nil {
-		request.BenchChan <- time.Now()
+		select {
+		case request.BenchChan <- time.Now():
+		default:
+		}
 	}
 
 	if err != nil {
 		return nil, err
 	}
 
-	var response *RestRequestResponse
-
-	response = &RestRequestResponse{
+	response := &RestRequestResponse{
 		HTTPCode: resp.StatusCode,
 		Headers:  &resp.Header,
+		Response: resp,
 	}
 
 	// Read and validate headers
-	statusStr := resp.Header.Get(HeaderStatus)
+	statusStr := strings.TrimSpace(resp.Header.Get(HeaderStatus))
 	statusMessage := resp.Header.Get(HeaderStatusMessage)
 
-	if len(statusStr) == 0 {
-		return response, ErrInvalidResponseHeaders
-	}
-
 	statusInt, err := strconv.Atoi(statusStr)
 	if err != nil || (statusInt > 1 || statusInt < 0) {
+		closeBody(resp)
 		return response, ErrInvalidResponseHeaders
 	}
 
@@ -312,24 +521,28 @@ func (request Request) Do(retVar interface{}) (*RestRequestResponse, error) {
 
 	// Only fill retVar if response was successful
 	if response.Status == ResponseSuccess && retVar != nil {
-		// Read response
-		d, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			return nil, err
-		}
 		// Parse response into retVar
-		err = json.Unmarshal(d, &retVar)
-		if err != nil {
-			return nil, err
+		if err = json.NewDecoder(resp.Body).Decode(retVar); err != nil {
+			closeBody(resp)
+			return response, err
 		}
 	}
 
-	// Set response
-	response.Response = resp
-
-	if request.CloseBody {
-		resp.Body.Close()
+	// Error responses have no body worth keeping
+	if request.CloseBody || response.Status == ResponseError {
+		closeBody(resp)
 	}
 
 	return response, nil
 }
+
+// maxDrainBytes max amount of bytes read from a discarded body
+const maxDrainBytes = 4 << 10
+
+// closeBody drains and closes the body to allow reusing the
+// connection. Big or endless bodies, eg. streams, are only
+// drained partially and the connection gets closed instead
+func closeBody(resp *http.Response) {
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, maxDrainBytes))
+	resp.Body.Close()
+}
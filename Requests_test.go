@@ -0,0 +1,422 @@
+package libremotebuild
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// trackingTransport records whether response bodies got closed
+type trackingTransport struct {
+	mx     sync.Mutex
+	bodies []*trackedBody
+}
+
+type trackedBody struct {
+	io.ReadCloser
+	closed bool
+}
+
+func (tb *trackedBody) Close() error {
+	tb.closed = true
+	return tb.ReadCloser.Close()
+}
+
+func (tt *trackingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body := &trackedBody{ReadCloser: resp.Body}
+	resp.Body = body
+
+	tt.mx.Lock()
+	tt.bodies = append(tt.bodies, body)
+	tt.mx.Unlock()
+
+	return resp, nil
+}
+
+func (tt *trackingTransport) allClosed() bool {
+	tt.mx.Lock()
+	defer tt.mx.Unlock()
+
+	for _, body := range tt.bodies {
+		if !body.closed {
+			return false
+		}
+	}
+
+	return len(tt.bodies) > 0
+}
+
+// newTestLibRB returns a LibRB talking to a server using handler
+func newTestLibRB(t *testing.T, handler http.HandlerFunc) (*LibRB, *trackingTransport) {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	transport := &trackingTransport{}
+	librb := NewLibRB(&RequestConfig{
+		URL:          server.URL,
+		SessionToken: "token",
+	}).WithTransport(transport).WithRetryPolicy(nil)
+
+	return librb, transport
+}
+
+func respond(status, message string, code int, body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if len(status) > 0 {
+			w.Header().Set(HeaderStatus, status)
+		}
+		w.Header().Set(HeaderStatusMessage, message)
+		w.WriteHeader(code)
+		io.WriteString(w, body)
+	}
+}
+
+func TestDoResponses(t *testing.T) {
+	largeString := strings.Repeat("a", 4<<20)
+
+	tests := []struct {
+		name        string
+		handler     http.HandlerFunc
+		retVar      bool
+		wantErr     error
+		wantAnyErr  bool
+		wantStatus  ResponseStatus
+		wantMessage string
+		wantContent string
+	}{
+		{
+			name:    "missing status header",
+			handler: respond("", "", http.StatusOK, `{"content":"x"}`),
+			retVar:  true,
+			wantErr: ErrInvalidResponseHeaders,
+		},
+		{
+			name:    "non numeric status header",
+			handler: respond("ok", "", http.StatusOK, ""),
+			wantErr: ErrInvalidResponseHeaders,
+		},
+		{
+			name:    "status out of range",
+			handler: respond("2", "", http.StatusOK, ""),
+			wantErr: ErrInvalidResponseHeaders,
+		},
+		{
+			name:    "negative status",
+			handler: respond("-1", "", http.StatusOK, ""),
+			wantErr: ErrInvalidResponseHeaders,
+		},
+		{
+			name:    "proxy error page",
+			handler: respond("", "", http.StatusBadGateway, "<html>Bad Gateway</html>"),
+			retVar:  true,
+			wantErr: ErrInvalidResponseHeaders,
+		},
+		{
+			name:        "success with json",
+			handler:     respond("1", "success", http.StatusOK, `{"content":"pong"}`),
+			retVar:      true,
+			wantStatus:  ResponseSuccess,
+			wantMessage: "success",
+			wantContent: "pong",
+		},
+		{
+			name:        "status with whitespace",
+			handler:     respond(" 1 ", "success", http.StatusOK, `{"content":"pong"}`),
+			retVar:      true,
+			wantStatus:  ResponseSuccess,
+			wantMessage: "success",
+			wantContent: "pong",
+		},
+		{
+			name:        "error status with body",
+			handler:     respond("0", "Job not found", http.StatusNotFound, `{"content":"ignored"}`),
+			retVar:      true,
+			wantStatus:  ResponseError,
+			wantMessage: "Job not found",
+		},
+		{
+			name:       "success with non json body",
+			handler:    respond("1", "success", http.StatusOK, "plain text"),
+			retVar:     true,
+			wantAnyErr: true,
+			wantStatus: ResponseSuccess,
+		},
+		{
+			name:       "success with empty body",
+			handler:    respond("1", "success", http.StatusOK, ""),
+			retVar:     true,
+			wantAnyErr: true,
+			wantStatus: ResponseSuccess,
+		},
+		{
+			name:        "success without retVar ignores body",
+			handler:     respond("1", "done", http.StatusOK, "plain text"),
+			wantStatus:  ResponseSuccess,
+			wantMessage: "done",
+		},
+		{
+			name:        "large body",
+			handler:     respond("1", "success", http.StatusOK, `{"content":"`+largeString+`"}`),
+			retVar:      true,
+			wantStatus:  ResponseSuccess,
+			wantMessage: "success",
+			wantContent: largeString,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			librb, transport := newTestLibRB(t, test.handler)
+
+			var retVar *StringResponse
+			if test.retVar {
+				retVar = &StringResponse{}
+			}
+
+			var resp *RestRequestResponse
+			var err error
+			if retVar != nil {
+				resp, err = librb.NewRequest(EPPing, nil).Do(retVar)
+			} else {
+				resp, err = librb.NewRequest(EPPing, nil).Do(nil)
+			}
+
+			switch {
+			case test.wantErr != nil:
+				if !errors.Is(err, test.wantErr) {
+					t.Fatalf("expected %v, got %v", test.wantErr, err)
+				}
+			case test.wantAnyErr:
+				if err == nil {
+					t.Fatal("expected error")
+				}
+			case err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if resp == nil {
+				t.Fatal("expected response")
+			}
+			if test.wantErr == nil {
+				if resp.Status != test.wantStatus {
+					t.Errorf("expected status %d, got %d", test.wantStatus, resp.Status)
+				}
+				if resp.Message != test.wantMessage && len(test.wantMessage) > 0 {
+					t.Errorf("expected message %q, got %q", test.wantMessage, resp.Message)
+				}
+			}
+			if retVar != nil && retVar.String != test.wantContent {
+				t.Errorf("expected content of length %d, got %d", len(test.wantContent), len(retVar.String))
+			}
+
+			if !transport.allClosed() {
+				t.Error("response body not closed")
+			}
+		})
+	}
+}
+
+func TestDoKeepsBodyOpen(t *testing.T) {
+	librb, transport := newTestLibRB(t, respond("1", "success", http.StatusOK, "line 1\nline 2\n"))
+
+	resp, err := librb.NewRequest(EPJobLogs, nil).WithNoBodyClose().Do(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if transport.allClosed() {
+		t.Fatal("body closed unexpectedly")
+	}
+
+	b, err := ioutil.ReadAll(resp.Response.Body)
+	if err != nil || string(b) != "line 1\nline 2\n" {
+		t.Fatalf("unexpected body %q: %v", b, err)
+	}
+	resp.Response.Body.Close()
+}
+
+func TestDoClosesBodyOnError(t *testing.T) {
+	librb, transport := newTestLibRB(t, respond("0", "Job not found", http.StatusNotFound, "body"))
+
+	resp, err := librb.NewRequest(EPJobLogs, nil).WithNoBodyClose().Do(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Status != ResponseError {
+		t.Fatalf("expected error status, got %d", resp.Status)
+	}
+	if !transport.allClosed() {
+		t.Fatal("body of error response not closed")
+	}
+}
+
+func TestDoDoesNotDrainEndlessBody(t *testing.T) {
+	// Stream without protocol headers, eg. stripped by a proxy
+	librb, transport := newTestLibRB(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		for r.Context().Err() == nil {
+			if _, err := io.WriteString(w, strings.Repeat("data\n", 100)); err != nil {
+				return
+			}
+		}
+	})
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := librb.NewRequest(EPJobLogs, nil).Do(nil)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrInvalidResponseHeaders) {
+			t.Fatalf("expected ErrInvalidResponseHeaders, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Do blocked on draining the body")
+	}
+
+	if !transport.allClosed() {
+		t.Fatal("body not closed")
+	}
+}
+
+func TestDoPayloads(t *testing.T) {
+	pipeReader, pipeWriter := io.Pipe()
+	go func() {
+		pipeWriter.Write([]byte("piped"))
+		pipeWriter.Close()
+	}()
+
+	tests := []struct {
+		name        string
+		requestType RequestType
+		payload     interface{}
+		want        string
+	}{
+		{"json struct", JSONRequestType, JobRequest{JobID: 5}, `{"id":5}`},
+		{"json nil", JSONRequestType, nil, "null"},
+		{"raw bytes", RawRequestType, []byte("raw bytes"), "raw bytes"},
+		{"raw reader", RawRequestType, strings.NewReader("raw reader"), "raw reader"},
+		{"raw pipe", RawRequestType, pipeReader, "piped"},
+		{"raw nil", RawRequestType, nil, ""},
+		{"raw unsupported", RawRequestType, 42, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got []byte
+			librb, _ := newTestLibRB(t, func(w http.ResponseWriter, r *http.Request) {
+				got, _ = ioutil.ReadAll(r.Body)
+				respond("1", "success", http.StatusOK, "")(w, r)
+			})
+
+			_, err := librb.NewRequest(EPPing, test.payload).
+				WithRequestType(test.requestType).
+				Do(nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(bytes.TrimSpace(got)) != test.want {
+				t.Errorf("expected payload %q, got %q", test.want, got)
+			}
+		})
+	}
+}
+
+func TestDoRequestHeaders(t *testing.T) {
+	var req *http.Request
+	librb, _ := newTestLibRB(t, func(w http.ResponseWriter, r *http.Request) {
+		req = r
+		respond("1", "success", http.StatusOK, "")(w, r)
+	})
+	librb.Config.URL += "/api"
+
+	_, err := librb.NewRequest(EPJobInfo, JobRequest{JobID: 1}).
+		WithAuthFromConfig().
+		WithMethod(GET).
+		WithHeader("X-Custom", "value").
+		Do(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checks := map[string][2]string{
+		"method":        {string(GET), req.Method},
+		"path":          {"/api" + string(EPJobInfo), req.URL.Path},
+		"authorization": {"Bearer token", req.Header.Get("Authorization")},
+		"content type":  {string(JSONContentType), req.Header.Get("Content-Type")},
+		"custom header": {"value", req.Header.Get("X-Custom")},
+	}
+
+	for name, check := range checks {
+		if check[0] != check[1] {
+			t.Errorf("%s: expected %q, got %q", name, check[0], check[1])
+		}
+	}
+}
+
+func TestDoBenchCallbackDoesNotBlock(t *testing.T) {
+	librb, _ := newTestLibRB(t, respond("1", "success", http.StatusOK, ""))
+
+	// Nobody reads from bench
+	bench := make(chan time.Time)
+	done := make(chan struct{})
+	go func() {
+		librb.NewRequest(EPPing, nil).WithBenchCallback(bench).Do(nil)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Do blocked on bench channel")
+	}
+}
+
+func TestRequestErrors(t *testing.T) {
+	librb, _ := newTestLibRB(t, respond("0", "Invalid state: job already finished", http.StatusConflict, ""))
+
+	err := librb.CancelJob(1)
+	if !errors.Is(err, ErrInvalidState) {
+		t.Fatalf("expected ErrInvalidState, got %v", err)
+	}
+	if !errors.Is(err, ErrResponseError) {
+		t.Fatalf("expected ErrResponseError, got %v", err)
+	}
+
+	var respErr *ResponseErr
+	if !errors.As(err, &respErr) || respErr.Response.HTTPCode != http.StatusConflict {
+		t.Fatalf("expected *ResponseErr with code 409, got %v", err)
+	}
+}
+
+func TestRequestDecodesIntoRetVar(t *testing.T) {
+	info := JobInfo{ID: 3, Status: JobRunning, Position: 2}
+	librb, _ := newTestLibRB(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(HeaderStatus, "1")
+		json.NewEncoder(w).Encode(info)
+	})
+
+	got, err := librb.JobInfo(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(*got, info) {
+		t.Fatalf("expected %+v, got %+v", info, *got)
+	}
+}
@@ -30,6 +30,15 @@ const (
 
 	// HeaderContentLength request content length
 	HeaderContentLength string = "ContentLength"
+
+	// HeaderRetryAfter time to wait before retrying a request
+	HeaderRetryAfter string = "Retry-After"
+
+	// HeaderIdempotencyKey key marking a non idempotent request as safe to retry
+	HeaderIdempotencyKey string = "Idempotency-Key"
+
+	// HeaderLastEventID ID of the last received event
+	HeaderLastEventID string = "Last-Event-ID"
 )
 
 // LoginResponse response for login
@@ -58,8 +67,29 @@ type StringSliceResponse struct {
 
 // AddJobResponse response for adding a job
 type AddJobResponse struct {
-	ID       uint `json:"id"`
-	Position int  `json:"pos"`
+	ID             uint   `json:"id"`
+	Position       int    `json:"pos"`
+	IdempotencyKey string `json:"idempotencykey,omitempty"`
+}
+
+// UploadResponse response for uploaded job sources
+type UploadResponse struct {
+	ID   string `json:"id"`
+	Size int64  `json:"size"`
+}
+
+// Artifact a file produced by a job
+type Artifact struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+	// Signature optional detached GPG signature
+	Signature []byte `json:"sig,omitempty"`
+}
+
+// ListArtifactsResponse artifacts of a job
+type ListArtifactsResponse struct {
+	Artifacts []Artifact `json:"artifacts"`
 }
 
 // JobInfo info of job
@@ -72,11 +102,49 @@ type JobInfo struct {
 	Status       JobState      `json:"state"`
 	RunningSince time.Time     `json:"rs,omitempty"`
 	Duration     time.Duration `json:"dr"`
+	Artifacts    []Artifact    `json:"artifacts,omitempty"`
+	// Repository state of the pacman repository the packages were published to
+	Repository *RepositoryState `json:"repo,omitempty"`
+
+	Created  time.Time `json:"created,omitempty"`
+	Started  time.Time `json:"started,omitempty"`
+	Finished time.Time `json:"finished,omitempty"`
+	// User who submitted the job
+	User string `json:"user,omitempty"`
+	// Args of the job. Secrets are redacted using RedactArgs
+	Args map[string]string `json:"args,omitempty"`
+	// ExitCode of the build. Nil if the job didn't finish
+	ExitCode      *int   `json:"exit,omitempty"`
+	FailureReason string `json:"reason,omitempty"`
+	// Packages built by the job
+	Packages []Package  `json:"packages,omitempty"`
+	Ccache   *CcacheRun `json:"ccache,omitempty"`
+	// Worker which executed the job
+	Worker string `json:"worker,omitempty"`
+}
+
+// CcacheRun ccache stats of a single job
+type CcacheRun struct {
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
+}
+
+// HitRate returns the share of cache hits between 0 and 1
+func (run CcacheRun) HitRate() float64 {
+	if run.Hits+run.Misses == 0 {
+		return 0
+	}
+
+	return float64(run.Hits) / float64(run.Hits+run.Misses)
 }
 
 // ListJobsResponse list of queued jobs
 type ListJobsResponse struct {
 	Jobs []JobInfo `json:"jobs"`
+	// NextCursor cursor of the next page. Empty on the last page
+	NextCursor string `json:"next,omitempty"`
+	// Total amount of jobs matching the filters
+	Total int `json:"total,omitempty"`
 }
 
 // SortByJob sort jobs
@@ -0,0 +1,136 @@
+package libremotebuild
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy decides whether a failed attempt gets retried. Only GET
+// requests and requests carrying an idempotency key are retried
+type RetryPolicy interface {
+	// Retry returns the time to wait before the next attempt and
+	// whether to retry at all. attempt starts at 1. Either resp or
+	// err is set, depending on the outcome of the attempt
+	Retry(attempt int, resp *http.Response, err error) (time.Duration, bool)
+}
+
+// BackoffRetry retries with exponential backoff and jitter on
+// connection errors and on the configured http status codes
+type BackoffRetry struct {
+	// MaxAttempts total amount of attempts including the first one
+	MaxAttempts int
+	MinDelay    time.Duration
+	// MaxDelay max delay between attempts. Requests asked to
+	// wait longer using Retry-After are not retried. Values
+	// <= 0 don't limit the delay
+	MaxDelay time.Duration
+	// Jitter randomizes each delay by up to the given fraction (0-1)
+	Jitter float64
+	// StatusCodes http status codes to retry on
+	StatusCodes []int
+}
+
+// DefaultRetryPolicy returns the policy used by NewLibRB
+func DefaultRetryPolicy() *BackoffRetry {
+	return &BackoffRetry{
+		MaxAttempts: 4,
+		MinDelay:    500 * time.Millisecond,
+		MaxDelay:    30 * time.Second,
+		Jitter:      0.2,
+		StatusCodes: []int{
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+	}
+}
+
+// Retry implements RetryPolicy
+func (policy *BackoffRetry) Retry(attempt int, resp *http.Response, err error) (time.Duration, bool) {
+	if attempt >= policy.MaxAttempts {
+		return 0, false
+	}
+
+	if err != nil {
+		// Don't retry cancelled requests
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return 0, false
+		}
+
+		return policy.delay(attempt), true
+	}
+
+	if resp == nil || !policy.retryStatus(resp.StatusCode) {
+		return 0, false
+	}
+
+	// Respect servers wish. Give up if it asks to wait longer than
+	// MaxDelay, the caller can use ResponseErr.RetryAfter instead
+	if retryAfter, ok := parseRetryAfter(resp.Header.Get(HeaderRetryAfter)); ok {
+		if policy.MaxDelay > 0 && retryAfter > policy.MaxDelay {
+			return 0, false
+		}
+		return retryAfter, true
+	}
+
+	return policy.delay(attempt), true
+}
+
+func (policy *BackoffRetry) retryStatus(code int) bool {
+	for _, c := range policy.StatusCodes {
+		if c == code {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (policy *BackoffRetry) delay(attempt int) time.Duration {
+	d := float64(policy.MinDelay) * math.Pow(2, float64(attempt-1))
+	if policy.MaxDelay > 0 && d > float64(policy.MaxDelay) {
+		d = float64(policy.MaxDelay)
+	}
+
+	if policy.Jitter > 0 {
+		d += d * policy.Jitter * (2*rand.Float64() - 1)
+	}
+
+	return time.Duration(d)
+}
+
+// parseRetryAfter parses a Retry-After header
+// value given in seconds or as http date
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if len(value) == 0 {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		d := time.Until(date)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+
+	return 0, false
+}
+
+// WithRetryPolicy use policy for all requests. Pass nil to disable retries
+func (librb *LibRB) WithRetryPolicy(policy RetryPolicy) *LibRB {
+	librb.RetryPolicy = policy
+	return librb
+}
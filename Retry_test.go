@@ -0,0 +1,161 @@
+package libremotebuild
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       Method
+		payload      interface{}
+		requestType  RequestType
+		key          bool
+		wantAttempts int32
+	}{
+		{"get", GET, nil, JSONRequestType, false, 3},
+		{"put without key", PUT, nil, JSONRequestType, false, 1},
+		{"post without key", POST, nil, JSONRequestType, false, 1},
+		{"put with key", PUT, nil, JSONRequestType, true, 3},
+		{"raw bytes", GET, []byte("data"), RawRequestType, false, 3},
+		{"raw reader", GET, strings.NewReader("data"), RawRequestType, false, 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var attempts int32
+			librb, _ := newTestLibRB(t, func(w http.ResponseWriter, r *http.Request) {
+				// Fail the first two attempts
+				if atomic.AddInt32(&attempts, 1) < 3 {
+					w.Header().Set(HeaderRetryAfter, "0")
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+
+				respond("1", "success", http.StatusOK, "")(w, r)
+			})
+			librb.WithRetryPolicy(&BackoffRetry{
+				MaxAttempts: 5,
+				MinDelay:    time.Millisecond,
+				MaxDelay:    time.Millisecond,
+				StatusCodes: []int{http.StatusServiceUnavailable},
+			})
+
+			req := librb.NewRequest(EPPing, test.payload).
+				WithMethod(test.method).
+				WithRequestType(test.requestType)
+			if test.key {
+				req.WithHeader(HeaderIdempotencyKey, NewIdempotencyKey())
+			}
+
+			req.Do(nil)
+
+			if got := atomic.LoadInt32(&attempts); got != test.wantAttempts {
+				t.Errorf("expected %d attempts, got %d", test.wantAttempts, got)
+			}
+		})
+	}
+}
+
+func TestRetryMaxAttempts(t *testing.T) {
+	var attempts int32
+	librb, transport := newTestLibRB(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusBadGateway)
+		io.WriteString(w, "Bad Gateway")
+	})
+	librb.WithRetryPolicy(&BackoffRetry{
+		MaxAttempts: 3,
+		MinDelay:    time.Millisecond,
+		MaxDelay:    time.Millisecond,
+		StatusCodes: []int{http.StatusBadGateway},
+	})
+
+	_, err := librb.JobInfo(1)
+	if got := atomic.LoadInt32(&attempts); got != 3 {
+		t.Errorf("expected 3 attempts, got %d", got)
+	}
+	if !errors.Is(err, ErrServerUnavailable) {
+		t.Errorf("expected ErrServerUnavailable, got %v", err)
+	}
+	if !transport.allClosed() {
+		t.Error("response body not closed")
+	}
+}
+
+func TestRetryAfterExceedsMaxDelay(t *testing.T) {
+	var attempts int32
+	librb, _ := newTestLibRB(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.Header().Set(HeaderRetryAfter, "86400")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	librb.WithRetryPolicy(&BackoffRetry{
+		MaxAttempts: 3,
+		MinDelay:    time.Millisecond,
+		MaxDelay:    time.Second,
+		StatusCodes: []int{http.StatusServiceUnavailable},
+	})
+
+	start := time.Now()
+	_, err := librb.JobInfo(1)
+	if got := atomic.LoadInt32(&attempts); got != 1 || time.Since(start) > 5*time.Second {
+		t.Errorf("expected 1 attempt without waiting, got %d", got)
+	}
+
+	var respErr *ResponseErr
+	if !errors.As(err, &respErr) {
+		t.Fatalf("expected *ResponseErr, got %v", err)
+	}
+	if retryAfter, ok := respErr.RetryAfter(); !ok || retryAfter != 24*time.Hour {
+		t.Errorf("unexpected retry after %v", retryAfter)
+	}
+}
+
+func TestRetryWithoutMaxDelay(t *testing.T) {
+	policy := &BackoffRetry{MaxAttempts: 4, MinDelay: time.Second}
+
+	for attempt := 1; attempt < 4; attempt++ {
+		delay, ok := policy.Retry(attempt, nil, errors.New("connection reset"))
+		if expected := time.Second << uint(attempt-1); !ok || delay != expected {
+			t.Errorf("attempt %d: expected %v, got %v %v", attempt, expected, delay, ok)
+		}
+	}
+
+	resp := &http.Response{StatusCode: http.StatusServiceUnavailable, Header: http.Header{}}
+	resp.Header.Set(HeaderRetryAfter, "120")
+	policy.StatusCodes = []int{http.StatusServiceUnavailable}
+	if delay, ok := policy.Retry(1, resp, nil); !ok || delay != 2*time.Minute {
+		t.Errorf("expected Retry-After to be used, got %v %v", delay, ok)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		value string
+		ok    bool
+		min   time.Duration
+		max   time.Duration
+	}{
+		{"", false, 0, 0},
+		{"abc", false, 0, 0},
+		{"-1", false, 0, 0},
+		{"0", true, 0, 0},
+		{"120", true, 120 * time.Second, 120 * time.Second},
+		{time.Now().Add(time.Minute).UTC().Format(http.TimeFormat), true, 50 * time.Second, time.Minute},
+		{time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat), true, 0, 0},
+	}
+
+	for _, test := range tests {
+		d, ok := parseRetryAfter(test.value)
+		if ok != test.ok || d < test.min || d > test.max {
+			t.Errorf("%q: got %v %v", test.value, d, ok)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package libremotebuild
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+var (
+	s3BucketRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$`)
+	s3RegionRegex = regexp.MustCompile(`^[a-z0-9-]+$`)
+)
+
+// S3Target upload to a bucket of S3 compatible object storage.
+// Either credentials or a secret reference have to be set
+type S3Target struct {
+	Endpoint  string
+	Bucket    string
+	Prefix    string
+	Region    string
+	AccessKey string
+	SecretKey string
+	// SecretRef name of a secret on the server holding the credentials
+	SecretRef string
+	// PathStyle use path style addressing, required by MinIO
+	PathStyle bool
+}
+
+// NewS3Target upload to bucket at endpoint
+func NewS3Target(endpoint, bucket string) *S3Target {
+	return &S3Target{
+		Endpoint: endpoint,
+		Bucket:   bucket,
+	}
+}
+
+// WithPrefix store the packages below prefix
+func (target *S3Target) WithPrefix(prefix string) *S3Target {
+	target.Prefix = prefix
+	return target
+}
+
+// WithRegion sets the region of the bucket
+func (target *S3Target) WithRegion(region string) *S3Target {
+	target.Region = region
+	return target
+}
+
+// WithCredentials authenticate using an access key. The
+// keys are sent in plaintext as args of the job
+func (target *S3Target) WithCredentials(accessKey, secretKey string) *S3Target {
+	target.AccessKey = accessKey
+	target.SecretKey = secretKey
+	target.SecretRef = ""
+	return target
+}
+
+// WithSecretRef authenticate using the credentials stored
+// in the secret ref on the server
+func (target *S3Target) WithSecretRef(ref string) *S3Target {
+	target.SecretRef = ref
+	target.AccessKey = ""
+	target.SecretKey = ""
+	return target
+}
+
+// WithPathStyle use path style addressing
+func (target *S3Target) WithPathStyle() *S3Target {
+	target.PathStyle = true
+	return target
+}
+
+// UploadType implements UploadTarget
+func (target *S3Target) UploadType() UploadType {
+	return S3UploadType
+}
+
+// Args implements UploadTarget
+func (target *S3Target) Args() map[string]string {
+	args := map[string]string{
+		S3Endpoint: target.Endpoint,
+		S3Bucket:   target.Bucket,
+	}
+
+	optional := map[string]string{
+		S3Prefix:    target.Prefix,
+		S3Region:    target.Region,
+		S3AccessKey: target.AccessKey,
+		S3SecretKey: target.SecretKey,
+		S3SecretRef: target.SecretRef,
+	}
+	for k, v := range optional {
+		if len(v) > 0 {
+			args[k] = v
+		}
+	}
+
+	if target.PathStyle {
+		args[S3PathStyle] = "true"
+	}
+
+	return args
+}
+
+// Validate implements UploadTarget
+func (target *S3Target) Validate() error {
+	return target.UploadType().ValidateArgs(target.Args())
+}
+
+// validateS3Args validates the args of an S3UploadType
+func validateS3Args(args map[string]string) error {
+	u, err := url.Parse(args[S3Endpoint])
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+		return fmt.Errorf("%w: invalid endpoint %q", ErrInvalidUploadTarget, args[S3Endpoint])
+	}
+
+	bucket := args[S3Bucket]
+	if !s3BucketRegex.MatchString(bucket) || strings.Contains(bucket, "..") || net.ParseIP(bucket) != nil {
+		return fmt.Errorf("%w: invalid bucket name %q", ErrInvalidUploadTarget, bucket)
+	}
+
+	if prefix, ok := args[S3Prefix]; ok {
+		clean := path.Clean(prefix)
+		if strings.HasPrefix(prefix, "/") || clean == ".." || strings.HasPrefix(clean, "../") {
+			return fmt.Errorf("%w: invalid prefix %q", ErrInvalidUploadTarget, prefix)
+		}
+	}
+
+	if region, ok := args[S3Region]; ok && !s3RegionRegex.MatchString(region) {
+		return fmt.Errorf("%w: invalid region %q", ErrInvalidUploadTarget, region)
+	}
+
+	hasKeys := len(args[S3AccessKey]) > 0 || len(args[S3SecretKey]) > 0
+	hasRef := len(args[S3SecretRef]) > 0
+	switch {
+	case hasKeys && hasRef:
+		return fmt.Errorf("%w: use either credentials or a secret reference", ErrInvalidUploadTarget)
+	case hasKeys && (len(args[S3AccessKey]) == 0 || len(args[S3SecretKey]) == 0):
+		return fmt.Errorf("%w: access key and secret key are required", ErrInvalidUploadTarget)
+	case !hasKeys && !hasRef:
+		return fmt.Errorf("%w: credentials or a secret reference are required", ErrInvalidUploadTarget)
+	}
+
+	if style, ok := args[S3PathStyle]; ok && style != "true" && style != "false" {
+		return fmt.Errorf("%w: invalid path style %q", ErrInvalidUploadTarget, style)
+	}
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package libremotebuild
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestS3TargetValidate(t *testing.T) {
+	const endpoint = "http://localhost:9000"
+
+	tests := []struct {
+		name    string
+		target  *S3Target
+		wantErr bool
+	}{
+		{"credentials", NewS3Target(endpoint, "packages").WithCredentials("key", "secret"), false},
+		{"secret ref", NewS3Target("https://s3.example.com", "pkg.mirror").WithSecretRef("mirror").WithRegion("eu-central-1").WithPrefix("x86_64/").WithPathStyle(), false},
+		{"no credentials", NewS3Target(endpoint, "packages"), true},
+		{"missing secret key", NewS3Target(endpoint, "packages").WithCredentials("key", ""), true},
+		{"both", &S3Target{Endpoint: endpoint, Bucket: "packages", AccessKey: "key", SecretKey: "secret", SecretRef: "mirror"}, true},
+		{"no scheme", NewS3Target("localhost:9000", "packages").WithSecretRef("mirror"), true},
+		{"empty endpoint", NewS3Target("", "packages").WithSecretRef("mirror"), true},
+		{"uppercase bucket", NewS3Target(endpoint, "Packages").WithSecretRef("mirror"), true},
+		{"short bucket", NewS3Target(endpoint, "pk").WithSecretRef("mirror"), true},
+		{"ip bucket", NewS3Target(endpoint, "192.168.1.1").WithSecretRef("mirror"), true},
+		{"absolute prefix", NewS3Target(endpoint, "packages").WithSecretRef("mirror").WithPrefix("/repo"), true},
+		{"escaping prefix", NewS3Target(endpoint, "packages").WithSecretRef("mirror").WithPrefix("../repo"), true},
+		{"invalid region", NewS3Target(endpoint, "packages").WithSecretRef("mirror").WithRegion("EU 1"), true},
+	}
+
+	for _, test := range tests {
+		err := test.target.Validate()
+		if (err != nil) != test.wantErr {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+		}
+		if err != nil && !errors.Is(err, ErrInvalidUploadTarget) {
+			t.Errorf("%s: expected ErrInvalidUploadTarget, got %v", test.name, err)
+		}
+	}
+
+	// Credentials are replaced by a secret ref
+	target := NewS3Target(endpoint, "packages").WithCredentials("key", "secret").WithSecretRef("mirror")
+	args := target.Args()
+	if _, ok := args[S3SecretKey]; ok || args[S3SecretRef] != "mirror" || S3UploadType.String() != "S3" {
+		t.Fatalf("unexpected args %v", args)
+	}
+}
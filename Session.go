@@ -0,0 +1,85 @@
+package libremotebuild
+
+import (
+	"context"
+	"net/http"
+)
+
+// TokenRefresher obtains a new session token after the server rejected
+// the current one with 401 Unauthorized. librb has no TokenRefresher, so
+// rejected requests made by the refresher fail instead of refreshing again
+type TokenRefresher func(ctx context.Context, librb LibRB) (string, error)
+
+// CredentialsFunc returns credentials to login with
+type CredentialsFunc func(ctx context.Context) (username, password string, err error)
+
+// WithTokenRefresher use refresher to renew expired sessions
+func (librb *LibRB) WithTokenRefresher(refresher TokenRefresher) *LibRB {
+	librb.TokenRefresher = refresher
+	return librb
+}
+
+// LoginRefresher returns a TokenRefresher which logs in
+// again using the credentials returned by credentials
+func LoginRefresher(credentials CredentialsFunc) TokenRefresher {
+	return func(ctx context.Context, librb LibRB) (string, error) {
+		username, password, err := credentials(ctx)
+		if err != nil {
+			return "", err
+		}
+
+		resp, err := librb.LoginContext(ctx, username, password)
+		if err != nil {
+			return "", err
+		}
+
+		return resp.Token, nil
+	}
+}
+
+// RefreshSession obtains a new session token using the TokenRefresher
+// and stores it in the config. Concurrent calls for the same rejected
+// token only refresh the session once
+func (librb LibRB) RefreshSession(ctx context.Context, rejected string) error {
+	if librb.TokenRefresher == nil {
+		return ErrUnauthorized
+	}
+
+	librb.Config.refreshMx.Lock()
+	defer librb.Config.refreshMx.Unlock()
+
+	// Session was already refreshed by another request
+	if librb.Config.GetSessionToken() != rejected {
+		return nil
+	}
+
+	// Requests of the refresher must not refresh the
+	// session again, refreshMx is held until it returns
+	refresher := librb.TokenRefresher
+	librb.TokenRefresher = nil
+
+	token, err := refresher(ctx, librb)
+	if err != nil {
+		return err
+	}
+
+	librb.Config.SetSessionToken(token)
+	return nil
+}
+
+func (request *Request) canRefresh() bool {
+	_, ok := request.AuthProvider.(RefreshableAuthProvider)
+	return ok && request.sentAuth != nil && request.isReplayable()
+}
+
+// refreshAndReplay refreshes the authorization and sends the request
+// again. Returns the original response if refreshing failed
+func (request *Request) refreshAndReplay(resp *http.Response) (*http.Response, error) {
+	provider := request.AuthProvider.(RefreshableAuthProvider)
+	if err := provider.Refresh(request.getContext(), request.sentAuth); err != nil {
+		return resp, nil
+	}
+
+	closeBody(resp)
+	return request.DoHTTPRequest()
+}
@@ -0,0 +1,62 @@
+package libremotebuild
+
+// UploadTarget destination the built packages get uploaded to
+type UploadTarget interface {
+	// UploadType returns the registered type of the target
+	UploadType() UploadType
+	// Args returns the settings of the target as job args
+	Args() map[string]string
+	// Validate checks the settings before the job gets submitted
+	Validate() error
+}
+
+// DataManagerTarget upload to a DataManager instance
+type DataManagerTarget struct {
+	Username  string
+	Token     string
+	Host      string
+	Namespace string
+}
+
+// UploadType implements UploadTarget
+func (target DataManagerTarget) UploadType() UploadType {
+	return DataManagerUploadType
+}
+
+// Args implements UploadTarget
+func (target DataManagerTarget) Args() map[string]string {
+	args := map[string]string{
+		DMUser:  target.Username,
+		DMToken: target.Token,
+		DMHost:  target.Host,
+	}
+
+	if len(target.Namespace) > 0 {
+		args[DMNamespace] = target.Namespace
+	}
+
+	return args
+}
+
+// Validate implements UploadTarget
+func (target DataManagerTarget) Validate() error {
+	return target.UploadType().ValidateArgs(target.Args())
+}
+
+// LocalStorageTarget keep the packages on the server
+type LocalStorageTarget struct{}
+
+// UploadType implements UploadTarget
+func (LocalStorageTarget) UploadType() UploadType {
+	return LocalStorage
+}
+
+// Args implements UploadTarget
+func (LocalStorageTarget) Args() map[string]string {
+	return nil
+}
+
+// Validate implements UploadTarget
+func (LocalStorageTarget) Validate() error {
+	return nil
+}
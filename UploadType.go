@@ -1,6 +1,11 @@
 package libremotebuild
 
-import "strings"
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
 
 // UploadType type of upload destination
 type UploadType uint8
@@ -10,16 +15,97 @@ const (
 	NoUploadType UploadType = iota
 	DataManagerUploadType
 	LocalStorage
+	// S3UploadType upload to S3 compatible object storage
+	S3UploadType
+)
+
+var (
+	// ErrInvalidUploadTarget upload settings are invalid
+	ErrInvalidUploadTarget = errors.New("invalid upload target")
+	// ErrUploadTypeRegistered upload type or name already registered
+	ErrUploadTypeRegistered = errors.New("upload type already registered")
 )
 
+// UploadTypeInfo describes a registered upload type
+type UploadTypeInfo struct {
+	Type UploadType
+	Name string
+	// RequiredArgs arg keys which must be set
+	RequiredArgs []string
+	// Validate optional validation of the args
+	Validate func(args map[string]string) error
+}
+
+var uploadTypes = struct {
+	sync.RWMutex
+	byType map[UploadType]UploadTypeInfo
+	byName map[string]UploadType
+}{
+	byType: make(map[UploadType]UploadTypeInfo),
+	byName: make(map[string]UploadType),
+}
+
+func init() {
+	MustRegisterUploadType(UploadTypeInfo{
+		Type: NoUploadType,
+		Name: "no upload",
+	})
+	MustRegisterUploadType(UploadTypeInfo{
+		Type:         DataManagerUploadType,
+		Name:         "DataManager",
+		RequiredArgs: []string{DMUser, DMToken, DMHost},
+	})
+	MustRegisterUploadType(UploadTypeInfo{
+		Type: LocalStorage,
+		Name: "LocalStorage",
+	})
+	MustRegisterUploadType(UploadTypeInfo{
+		Type:         S3UploadType,
+		Name:         "S3",
+		RequiredArgs: []string{S3Endpoint, S3Bucket},
+		Validate:     validateS3Args,
+	})
+}
+
+// RegisterUploadType registers a new upload type. Both the type
+// and its name have to be unique. Names are case insensitive
+func RegisterUploadType(info UploadTypeInfo) error {
+	uploadTypes.Lock()
+	defer uploadTypes.Unlock()
+
+	name := strings.ToLower(info.Name)
+
+	if _, ok := uploadTypes.byType[info.Type]; ok {
+		return fmt.Errorf("%w: %d", ErrUploadTypeRegistered, info.Type)
+	}
+	if _, ok := uploadTypes.byName[name]; ok {
+		return fmt.Errorf("%w: %s", ErrUploadTypeRegistered, info.Name)
+	}
+
+	uploadTypes.byType[info.Type] = info
+	uploadTypes.byName[name] = info.Type
+	return nil
+}
+
+// MustRegisterUploadType registers an upload type and panics on error
+func MustRegisterUploadType(info UploadTypeInfo) {
+	if err := RegisterUploadType(info); err != nil {
+		panic(err)
+	}
+}
+
+// LookupUploadType returns the info of a registered upload type
+func LookupUploadType(ut UploadType) (UploadTypeInfo, bool) {
+	uploadTypes.RLock()
+	defer uploadTypes.RUnlock()
+
+	info, ok := uploadTypes.byType[ut]
+	return info, ok
+}
+
 func (ut UploadType) String() string {
-	switch ut {
-	case NoUploadType:
-		return "no upload"
-	case DataManagerUploadType:
-		return "DataManager"
-	case LocalStorage:
-		return "LocalStorage"
+	if info, ok := LookupUploadType(ut); ok {
+		return info.Name
 	}
 
 	return "<invalid>"
@@ -29,12 +115,32 @@ func (ut UploadType) String() string {
 func ParseUploadType(s string) UploadType {
 	s = strings.ToLower(strings.TrimSpace(s))
 
-	switch s {
-	case strings.ToLower(DataManagerUploadType.String()):
-		return DataManagerUploadType
-	case strings.ToLower(LocalStorage.String()):
-		return LocalStorage
+	uploadTypes.RLock()
+	defer uploadTypes.RUnlock()
+
+	if ut, ok := uploadTypes.byName[s]; ok {
+		return ut
 	}
 
 	return NoUploadType
 }
+
+// ValidateArgs checks args against the requirements of the upload type
+func (ut UploadType) ValidateArgs(args map[string]string) error {
+	info, ok := LookupUploadType(ut)
+	if !ok {
+		return fmt.Errorf("%w: unknown upload type %d", ErrInvalidUploadTarget, ut)
+	}
+
+	for _, key := range info.RequiredArgs {
+		if len(args[key]) == 0 {
+			return fmt.Errorf("%w: %s requires %s", ErrInvalidUploadTarget, info.Name, key)
+		}
+	}
+
+	if info.Validate != nil {
+		return info.Validate(args)
+	}
+
+	return nil
+}
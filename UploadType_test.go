@@ -0,0 +1,106 @@
+package libremotebuild
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// bucketTarget test upload target
+type bucketTarget struct {
+	bucket string
+}
+
+const uploadBucket UploadType = 200
+
+func (bucketTarget) UploadType() UploadType {
+	return uploadBucket
+}
+
+func (target bucketTarget) Args() map[string]string {
+	return map[string]string{"BUCKET": target.bucket}
+}
+
+func (target bucketTarget) Validate() error {
+	return target.UploadType().ValidateArgs(target.Args())
+}
+
+func TestUploadTypeRegistry(t *testing.T) {
+	err := RegisterUploadType(UploadTypeInfo{
+		Type:         uploadBucket,
+		Name:         "Bucket",
+		RequiredArgs: []string{"BUCKET"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		unregisterUploadType(uploadBucket)
+	})
+
+	if uploadBucket.String() != "Bucket" || ParseUploadType(" bucket ") != uploadBucket {
+		t.Fatal("custom upload type not resolvable")
+	}
+	if ParseUploadType("datamanager") != DataManagerUploadType || LocalStorage.String() != "LocalStorage" {
+		t.Fatal("builtin upload type not resolvable")
+	}
+	if ParseUploadType("unknown") != NoUploadType || UploadType(201).String() != "<invalid>" {
+		t.Fatal("unknown upload type resolved")
+	}
+
+	if err := RegisterUploadType(UploadTypeInfo{Type: 201, Name: "localstorage"}); !errors.Is(err, ErrUploadTypeRegistered) {
+		t.Fatalf("expected duplicate name error, got %v", err)
+	}
+
+	tests := []struct {
+		target  UploadTarget
+		wantErr bool
+	}{
+		{bucketTarget{}, true},
+		{bucketTarget{bucket: "pkgs"}, false},
+		{DataManagerTarget{Username: "user", Host: "dm.example.com"}, true},
+		{DataManagerTarget{Username: "user", Token: "token", Host: "dm.example.com"}, false},
+		{LocalStorageTarget{}, false},
+	}
+
+	for _, test := range tests {
+		builder := LibRB{}.NewJobBuilder(JobAUR).WithArg(AURPackage, "yay").WithUploadTarget(test.target)
+		request, err := builder.BuildRequest()
+		if (err != nil) != test.wantErr {
+			t.Errorf("%+v: unexpected error %v", test.target, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+
+		if request.UploadType != test.target.UploadType() {
+			t.Errorf("%+v: unexpected upload type %v", test.target, request.UploadType)
+		}
+		for k, v := range test.target.Args() {
+			if request.Args[k] != v {
+				t.Errorf("%+v: arg %s not set", test.target, k)
+			}
+		}
+		if len(builder.Args()) != 1 {
+			t.Errorf("%+v: target args leaked into builder args", test.target)
+		}
+	}
+
+	// Upload types set without target are validated against the args
+	builder := LibRB{}.NewJobBuilder(JobAUR).WithArg(AURPackage, "yay").WithUploadType(DataManagerUploadType)
+	if err := builder.Validate(); !errors.Is(err, ErrInvalidUploadTarget) {
+		t.Fatalf("expected ErrInvalidUploadTarget, got %v", err)
+	}
+}
+
+// unregisterUploadType removes an upload type
+func unregisterUploadType(ut UploadType) {
+	uploadTypes.Lock()
+	defer uploadTypes.Unlock()
+
+	if info, ok := uploadTypes.byType[ut]; ok {
+		delete(uploadTypes.byName, strings.ToLower(info.Name))
+		delete(uploadTypes.byType, ut)
+	}
+}
@@ -1,9 +1,17 @@
 package libremotebuild
 
-import "strings"
+import (
+	"context"
+	"strings"
+)
 
 // Login login into the server
 func (librb LibRB) Login(username, password string) (*LoginResponse, error) {
+	return librb.LoginContext(context.Background(), username, password)
+}
+
+// LoginContext login into the server using ctx
+func (librb LibRB) LoginContext(ctx context.Context, username, password string) (*LoginResponse, error) {
 	var response LoginResponse
 
 	// Do http request
@@ -11,7 +19,8 @@ func (librb LibRB) Login(username, password string) (*LoginResponse, error) {
 		Password:  password,
 		Username:  strings.ToLower(username),
 		MachineID: librb.Config.MachineID,
-	}).Do(&response)
+	}).WithContext(ctx).
+		Do(&response)
 
 	// Return new error on ... error
 	if err != nil || resp.Status == ResponseError {
@@ -23,11 +32,17 @@ func (librb LibRB) Login(username, password string) (*LoginResponse, error) {
 
 // Register create a new account. Return true on success
 func (librb LibRB) Register(username, password string) (*RestRequestResponse, error) {
+	return librb.RegisterContext(context.Background(), username, password)
+}
+
+// RegisterContext create a new account using ctx
+func (librb LibRB) RegisterContext(ctx context.Context, username, password string) (*RestRequestResponse, error) {
 	// Do http request
 	resp, err := librb.NewRequest(EPRegister, CredentialsRequest{
 		Username: strings.ToLower(username),
 		Password: password,
-	}).Do(nil)
+	}).WithContext(ctx).
+		Do(nil)
 
 	if err != nil || resp.Status == ResponseError {
 		return resp, NewErrorFromResponse(resp, err)
@@ -39,16 +54,21 @@ func (librb LibRB) Register(username, password string) (*RestRequestResponse, er
 // Ping pings a server the REST way to
 // ensure it is reachable
 func (librb LibRB) Ping() (*StringResponse, error) {
+	return librb.PingContext(context.Background())
+}
+
+// PingContext pings a server using ctx
+func (librb LibRB) PingContext(ctx context.Context) (*StringResponse, error) {
 	var response StringResponse
 
 	// Do ping request
-	req := librb.NewRequest(EPPing, PingRequest{Payload: "ping"})
-	if librb.Config.SessionToken != "" {
+	req := librb.NewRequest(EPPing, PingRequest{Payload: "ping"}).WithContext(ctx)
+	if librb.AuthProvider != nil || librb.Config.GetSessionToken() != "" {
 		req.WithAuthFromConfig()
 	}
-	_, err := req.Do(&response)
-	if err != nil {
-		return nil, err
+	resp, err := req.Do(&response)
+	if err != nil || resp.Status == ResponseError {
+		return nil, NewErrorFromResponse(resp, err)
 	}
 
 	return &response, nil
@@ -0,0 +1,153 @@
+package libremotebuild
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// PartialSuffix suffix of artifacts which are not downloaded and verified yet
+const PartialSuffix = ".part"
+
+var (
+	// ErrChecksumMismatch downloaded data doesn't match the checksum of the artifact
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+	// ErrSignatureMissing artifact has no signature but a verifier is set
+	ErrSignatureMissing = errors.New("signature missing")
+	// ErrSignatureInvalid signature doesn't match or is not trusted
+	ErrSignatureInvalid = errors.New("invalid signature")
+)
+
+// VerificationError an artifact failed verification
+type VerificationError struct {
+	Artifact Artifact
+	// Err ErrChecksumMismatch, ErrSignatureMissing, ErrSignatureInvalid
+	// or the error which prevented verification
+	Err     error
+	Details string
+}
+
+func (verr *VerificationError) Error() string {
+	if len(verr.Details) > 0 {
+		return fmt.Sprintf("%s: %s: %s", verr.Artifact.Name, verr.Err.Error(), verr.Details)
+	}
+
+	return fmt.Sprintf("%s: %s", verr.Artifact.Name, verr.Err.Error())
+}
+
+// Unwrap returns Err
+func (verr *VerificationError) Unwrap() error {
+	return verr.Err
+}
+
+// SignatureVerifier verifies detached signatures of artifacts
+type SignatureVerifier interface {
+	// VerifySignature returns an error wrapping ErrSignatureInvalid
+	// if signature is no valid signature of file
+	VerifySignature(ctx context.Context, file string, signature []byte) error
+}
+
+// WithSignatureVerifier verify the signatures of downloaded artifacts
+// using v. Artifacts without signature are rejected
+func (librb *LibRB) WithSignatureVerifier(v SignatureVerifier) *LibRB {
+	librb.Verifier = v
+	return librb
+}
+
+// VerifyArtifact verifies the checksum of file and, if LibRB
+// has a SignatureVerifier, its signature
+func (librb LibRB) VerifyArtifact(ctx context.Context, artifact Artifact, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+
+	hasher := sha256.New()
+	_, err = io.Copy(hasher, f)
+	f.Close()
+	if err != nil {
+		return err
+	}
+
+	if err = verifyChecksum(artifact, hasher); err != nil {
+		return err
+	}
+
+	return librb.verifySignature(ctx, artifact, file)
+}
+
+func (librb LibRB) verifySignature(ctx context.Context, artifact Artifact, file string) error {
+	if librb.Verifier == nil {
+		return nil
+	}
+
+	if len(artifact.Signature) == 0 {
+		return &VerificationError{
+			Artifact: artifact,
+			Err:      ErrSignatureMissing,
+		}
+	}
+
+	if err := librb.Verifier.VerifySignature(ctx, file, artifact.Signature); err != nil {
+		return &VerificationError{
+			Artifact: artifact,
+			Err:      err,
+		}
+	}
+
+	return nil
+}
+
+// GPGVerifier verifies detached signatures using gpgv and a keyring
+type GPGVerifier struct {
+	// Keyring keyring file containing the trusted keys
+	Keyring string
+	// Binary gpgv binary to use. Defaults to "gpgv"
+	Binary string
+}
+
+// NewGPGVerifier returns a verifier trusting the keys of keyring
+func NewGPGVerifier(keyring string) *GPGVerifier {
+	return &GPGVerifier{
+		Keyring: keyring,
+	}
+}
+
+// VerifySignature implements SignatureVerifier
+func (verifier *GPGVerifier) VerifySignature(ctx context.Context, file string, signature []byte) error {
+	binary := verifier.Binary
+	if len(binary) == 0 {
+		binary = "gpgv"
+	}
+
+	sigFile, err := ioutil.TempFile("", "remotebuild-*.sig")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(sigFile.Name())
+
+	_, err = sigFile.Write(signature)
+	if cerr := sigFile.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	output, err := exec.CommandContext(ctx, binary, "--keyring", verifier.Keyring, sigFile.Name(), file).CombinedOutput()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return fmt.Errorf("%w: %s", ErrSignatureInvalid, strings.TrimSpace(string(output)))
+		}
+		return err
+	}
+
+	return nil
+}
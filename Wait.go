@@ -0,0 +1,91 @@
+package libremotebuild
+
+import (
+	"context"
+	"time"
+)
+
+// Default values for WaitOptions
+const (
+	DefaultPollInterval    = 2 * time.Second
+	DefaultMaxPollInterval = 30 * time.Second
+	DefaultPollMultiplier  = 1.5
+)
+
+// WaitOptions options for WaitForJob
+type WaitOptions struct {
+	// PollInterval initial time between two polls
+	PollInterval time.Duration
+	// MaxPollInterval upper limit for the poll interval
+	MaxPollInterval time.Duration
+	// Multiplier the interval gets multiplied with after each
+	// poll without changes. Changes reset the interval
+	Multiplier float64
+	// OnChange gets called on every state or position change
+	OnChange func(info JobInfo)
+}
+
+func (opts *WaitOptions) withDefaults() WaitOptions {
+	var o WaitOptions
+	if opts != nil {
+		o = *opts
+	}
+
+	if o.PollInterval <= 0 {
+		o.PollInterval = DefaultPollInterval
+	}
+	if o.MaxPollInterval < o.PollInterval {
+		o.MaxPollInterval = DefaultMaxPollInterval
+		if o.MaxPollInterval < o.PollInterval {
+			o.MaxPollInterval = o.PollInterval
+		}
+	}
+	if o.Multiplier < 1 {
+		o.Multiplier = DefaultPollMultiplier
+	}
+
+	return o
+}
+
+// WaitForJob blocks until the job reaches a terminal state and returns its
+// final info. A *JobError is returned for failed or cancelled jobs. opts can be nil
+func (librb LibRB) WaitForJob(ctx context.Context, jobID uint, opts *WaitOptions) (*JobInfo, error) {
+	o := opts.withDefaults()
+	interval := o.PollInterval
+
+	var last *JobInfo
+	for {
+		info, err := librb.JobInfoContext(ctx, jobID)
+		if err != nil {
+			return last, err
+		}
+
+		// Report changes and reset backoff
+		if last == nil || last.Status != info.Status || last.Position != info.Position {
+			if o.OnChange != nil {
+				o.OnChange(*info)
+			}
+			interval = o.PollInterval
+		} else if interval = time.Duration(float64(interval) * o.Multiplier); interval > o.MaxPollInterval {
+			interval = o.MaxPollInterval
+		}
+		last = info
+
+		switch info.Status {
+		case JobDone:
+			return info, nil
+		case JobFailed:
+			return info, &JobError{Job: info, Err: ErrJobFailed}
+		case JobCancelled:
+			return info, &JobError{Job: info, Err: ErrJobCancelled}
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return last, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package libremotebuild
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Webhook headers
+const (
+	// HeaderWebhookSignature HMAC-SHA256 signature of the payload
+	HeaderWebhookSignature string = "X-Remotebuild-Signature"
+
+	// HeaderWebhookTimestamp unix time the payload was signed at
+	HeaderWebhookTimestamp string = "X-Remotebuild-Timestamp"
+)
+
+// DefaultWebhookTolerance max age of webhook payloads
+const DefaultWebhookTolerance = 5 * time.Minute
+
+// maxWebhookPayload max size of a webhook payload
+const maxWebhookPayload = 1 << 20
+
+var (
+	// ErrInvalidWebhook webhook settings are invalid
+	ErrInvalidWebhook = errors.New("invalid webhook")
+	// ErrWebhookSignature webhook payload has no valid signature
+	ErrWebhookSignature = errors.New("invalid webhook signature")
+)
+
+// Webhook url notified about events of a job
+type Webhook struct {
+	URL string `json:"url"`
+	// Secret used to sign the payloads
+	Secret string `json:"secret"`
+	// Events to send. Defaults to JobFinishedEvent
+	Events []JobEventType `json:"events,omitempty"`
+}
+
+// Validate checks the webhook settings
+func (webhook Webhook) Validate() error {
+	u, err := url.Parse(webhook.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+		return fmt.Errorf("%w: invalid url %q", ErrInvalidWebhook, webhook.URL)
+	}
+
+	if len(webhook.Secret) == 0 {
+		return fmt.Errorf("%w: %s has no secret", ErrInvalidWebhook, webhook.URL)
+	}
+
+	return nil
+}
+
+// Wants returns true if the webhook has to be notified about eventType
+func (webhook Webhook) Wants(eventType JobEventType) bool {
+	if len(webhook.Events) == 0 {
+		return eventType == JobFinishedEvent
+	}
+
+	for _, e := range webhook.Events {
+		if e == eventType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SignWebhook returns the signature of a payload sent at timestamp. The
+// signature is "sha256=" followed by the hex encoded HMAC-SHA256 of
+// "<timestamp>.<payload>" keyed with secret
+func SignWebhook(secret string, timestamp time.Time, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10) + "."))
+	mac.Write(payload)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookPayload verifies the signature and age of a payload and
+// decodes it. Payloads older than tolerance are rejected to prevent
+// replays. A tolerance of 0 disables the check. Secret args of the
+// job info are redacted
+func VerifyWebhookPayload(secret string, payload []byte, signature, timestamp string, tolerance time.Duration) (*JobEvent, error) {
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid timestamp %q", ErrWebhookSignature, timestamp)
+	}
+
+	sentAt := time.Unix(unix, 0)
+	if tolerance > 0 {
+		if age := time.Since(sentAt); age > tolerance || age < -tolerance {
+			return nil, fmt.Errorf("%w: timestamp out of tolerance", ErrWebhookSignature)
+		}
+	}
+
+	expected := SignWebhook(secret, sentAt, payload)
+	if !hmac.Equal([]byte(expected), []byte(strings.TrimSpace(signature))) {
+		return nil, ErrWebhookSignature
+	}
+
+	var event JobEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, err
+	}
+
+	if event.Info != nil {
+		event.Info.Args = RedactArgs(event.Info.Args)
+	}
+
+	return &event, nil
+}
+
+// VerifyWebhook verifies and decodes the webhook
+// request r using DefaultWebhookTolerance
+func VerifyWebhook(r *http.Request, secret string) (*JobEvent, error) {
+	payload, err := ioutil.ReadAll(http.MaxBytesReader(nil, r.Body, maxWebhookPayload))
+	if err != nil {
+		return nil, err
+	}
+
+	return VerifyWebhookPayload(secret, payload,
+		r.Header.Get(HeaderWebhookSignature),
+		r.Header.Get(HeaderWebhookTimestamp),
+		DefaultWebhookTolerance)
+}
+
+// WebhookHandler returns a handler verifying incoming webhooks and
+// passing their events to fn. Requests with an invalid signature
+// are answered with 401, malformed requests with 400
+func WebhookHandler(secret string, fn func(JobEvent)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		event, err := VerifyWebhook(r, secret)
+		if err != nil {
+			if errors.Is(err, ErrWebhookSignature) {
+				w.WriteHeader(http.StatusUnauthorized)
+			} else {
+				w.WriteHeader(http.StatusBadRequest)
+			}
+			return
+		}
+
+		fn(*event)
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
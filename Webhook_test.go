@@ -0,0 +1,79 @@
+package libremotebuild
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestVerifyWebhookPayload(t *testing.T) {
+	const secret = "secret"
+	payload := []byte(`{"id":"1","type":"finished","job":3,"state":4}`)
+	now := time.Now()
+	signature := SignWebhook(secret, now, payload)
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+
+	event, err := VerifyWebhookPayload(secret, payload, signature, timestamp, DefaultWebhookTolerance)
+	if err != nil || event.Type != JobFinishedEvent || event.JobID != 3 || event.State != JobDone {
+		t.Fatalf("unexpected event %+v: %v", event, err)
+	}
+
+	// Secrets are redacted
+	secretPayload := []byte(`{"id":"2","type":"finished","job":3,"info":{"args":{"DM_Token":"token"}}}`)
+	event, err = VerifyWebhookPayload(secret, secretPayload, SignWebhook(secret, now, secretPayload), timestamp, DefaultWebhookTolerance)
+	if err != nil || event.Info.Args[DMToken] != redacted {
+		t.Fatalf("args not redacted %+v: %v", event.Info, err)
+	}
+
+	old := now.Add(-time.Hour)
+	tests := []struct {
+		name      string
+		secret    string
+		payload   []byte
+		signature string
+		timestamp string
+	}{
+		{"wrong secret", "other", payload, signature, timestamp},
+		{"tampered payload", secret, []byte(`{"job":4}`), signature, timestamp},
+		{"missing signature", secret, payload, "", timestamp},
+		{"invalid timestamp", secret, payload, signature, "now"},
+		{"expired", secret, payload, SignWebhook(secret, old, payload), strconv.FormatInt(old.Unix(), 10)},
+	}
+
+	for _, test := range tests {
+		_, err := VerifyWebhookPayload(test.secret, test.payload, test.signature, test.timestamp, DefaultWebhookTolerance)
+		if !errors.Is(err, ErrWebhookSignature) {
+			t.Errorf("%s: expected ErrWebhookSignature, got %v", test.name, err)
+		}
+	}
+}
+
+func TestWebhookHandler(t *testing.T) {
+	var received []JobEvent
+	handler := WebhookHandler("secret", func(event JobEvent) {
+		received = append(received, event)
+	})
+
+	payload := []byte(`{"type":"finished","job":3}`)
+	send := func(secret string) int {
+		now := time.Now()
+		r := httptest.NewRequest(http.MethodPost, "/hook", bytes.NewReader(payload))
+		r.Header.Set(HeaderWebhookTimestamp, strconv.FormatInt(now.Unix(), 10))
+		r.Header.Set(HeaderWebhookSignature, SignWebhook(secret, now, payload))
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	if code := send("other"); code != http.StatusUnauthorized || len(received) != 0 {
+		t.Fatalf("unexpected code %d", code)
+	}
+	if code := send("secret"); code != http.StatusNoContent || len(received) != 1 || received[0].JobID != 3 {
+		t.Fatalf("unexpected code %d, events %v", code, received)
+	}
+}
@@ -1,20 +1,34 @@
 package libremotebuild
 
+import (
+	"context"
+	"net/http"
+)
+
 // LibRB data required in all requests
 type LibRB struct {
-	Config *RequestConfig
+	Config         *RequestConfig
+	Client         *http.Client
+	RetryPolicy    RetryPolicy
+	TokenRefresher TokenRefresher
+	AuthProvider   AuthProvider
+	Middlewares    []Middleware
+	Verifier       SignatureVerifier
 }
 
 // NewLibRB create new libDM "class"
 func NewLibRB(config *RequestConfig) *LibRB {
-	return &LibRB{
-		Config: config,
-	}
+	return NewLibRBWithOptions(config, DefaultClientOptions())
 }
 
 // Request do a request using libdm
 func (libdm LibRB) Request(ep Endpoint, payload, response interface{}, authorized bool) (*RestRequestResponse, error) {
-	req := libdm.NewRequest(ep, payload)
+	return libdm.RequestContext(context.Background(), ep, payload, response, authorized)
+}
+
+// RequestContext do a request using libdm and ctx
+func (libdm LibRB) RequestContext(ctx context.Context, ep Endpoint, payload, response interface{}, authorized bool) (*RestRequestResponse, error) {
+	req := libdm.NewRequest(ep, payload).WithContext(ctx)
 	if authorized {
 		req.WithAuthFromConfig()
 	}
@@ -0,0 +1,137 @@
+package remotebuildtest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	libremotebuild "github.com/RemoteBuild/LibRemotebuild"
+)
+
+// artifact a file stored for a job
+type artifact struct {
+	libremotebuild.Artifact
+	content []byte
+}
+
+// AddArtifact adds an artifact to a job
+func (server *Server) AddArtifact(id uint, name string, content []byte) libremotebuild.Artifact {
+	sum := sha256.Sum256(content)
+	a := artifact{
+		Artifact: libremotebuild.Artifact{
+			Name:   name,
+			Size:   int64(len(content)),
+			SHA256: hex.EncodeToString(sum[:]),
+		},
+		content: content,
+	}
+
+	server.mx.Lock()
+	defer server.mx.Unlock()
+
+	if job, ok := server.jobs[id]; ok {
+		job.artifacts = append(job.artifacts, a)
+	}
+
+	return a.Artifact
+}
+
+// SignArtifact sets the signature of an artifact and returns the updated artifact
+func (server *Server) SignArtifact(id uint, name string, signature []byte) libremotebuild.Artifact {
+	server.mx.Lock()
+	defer server.mx.Unlock()
+
+	job, ok := server.jobs[id]
+	if !ok {
+		return libremotebuild.Artifact{}
+	}
+
+	for i := range job.artifacts {
+		if job.artifacts[i].Name == name {
+			job.artifacts[i].Signature = signature
+			return job.artifacts[i].Artifact
+		}
+	}
+
+	return libremotebuild.Artifact{}
+}
+
+func (server *Server) listArtifacts(w http.ResponseWriter, r *http.Request, body []byte, _ string) {
+	server.mx.Lock()
+	defer server.mx.Unlock()
+
+	job := server.requestedJob(w, body)
+	if job == nil {
+		return
+	}
+
+	response := libremotebuild.ListArtifactsResponse{
+		Artifacts: make([]libremotebuild.Artifact, 0, len(job.artifacts)),
+	}
+	for _, a := range job.artifacts {
+		response.Artifacts = append(response.Artifacts, a.Artifact)
+	}
+
+	sendResponse(w, response)
+}
+
+func (server *Server) downloadArtifact(w http.ResponseWriter, r *http.Request, body []byte, _ string) {
+	var req libremotebuild.ArtifactRequest
+	if !decode(w, body, &req) {
+		return
+	}
+
+	server.mx.Lock()
+	job, ok := server.jobs[req.JobID]
+	var content []byte
+	var found bool
+	if ok {
+		for _, a := range job.artifacts {
+			if a.Name == req.Name {
+				content, found = a.content, true
+			}
+		}
+	}
+	ignoreRange := server.IgnoreRange
+	cut := server.cutArtifact
+	server.cutArtifact = 0
+	server.mx.Unlock()
+
+	switch {
+	case !ok:
+		sendError(w, http.StatusNotFound, "Job not found")
+		return
+	case !found:
+		sendError(w, http.StatusNotFound, "Artifact not found")
+		return
+	}
+
+	if ignoreRange {
+		r.Header.Del("Range")
+	}
+
+	setStatus(w, libremotebuild.ResponseSuccess, "success")
+	if cut > 0 {
+		w = &cutWriter{ResponseWriter: w, left: cut}
+	}
+	http.ServeContent(w, r, req.Name, time.Time{}, bytes.NewReader(content))
+}
+
+// cutWriter drops the connection after left bytes were written
+type cutWriter struct {
+	http.ResponseWriter
+	left int64
+}
+
+func (cw *cutWriter) Write(p []byte) (int, error) {
+	if int64(len(p)) > cw.left {
+		cw.ResponseWriter.Write(p[:cw.left])
+		flush(cw.ResponseWriter)
+		panic(http.ErrAbortHandler)
+	}
+
+	cw.left -= int64(len(p))
+	return cw.ResponseWriter.Write(p)
+}
@@ -0,0 +1,125 @@
+package remotebuildtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	libremotebuild "github.com/RemoteBuild/LibRemotebuild"
+)
+
+// Events returns all events published so far
+func (server *Server) Events() []libremotebuild.JobEvent {
+	server.mx.Lock()
+	defer server.mx.Unlock()
+	return append([]libremotebuild.JobEvent(nil), server.events...)
+}
+
+// emitLocked publishes an event of job and notifies its webhooks.
+// IDs are the 1 based index of the event
+func (server *Server) emitLocked(job *Job, eventType libremotebuild.JobEventType, prev libremotebuild.JobState) {
+	info := server.infoLocked(job)
+
+	event := libremotebuild.JobEvent{
+		ID:        strconv.Itoa(len(server.events) + 1),
+		Type:      eventType,
+		Time:      time.Now(),
+		JobID:     info.ID,
+		State:     info.Status,
+		PrevState: prev,
+		Position:  info.Position,
+		Info:      &info,
+	}
+
+	server.events = append(server.events, event)
+	server.deliverWebhooksLocked(job, event)
+}
+
+// updatePositionsLocked publishes position changes of all jobs
+func (server *Server) updatePositionsLocked() {
+	for _, job := range server.sortedJobsLocked() {
+		position := server.infoLocked(job).Position
+
+		if position != job.position {
+			job.position = position
+			if position > 0 {
+				server.emitLocked(job, libremotebuild.JobPositionChangedEvent, job.Info.Status)
+			}
+		}
+	}
+}
+
+// jobEvents streams all events after the Last-Event-ID
+// matching the filter until the client disconnects
+func (server *Server) jobEvents(w http.ResponseWriter, r *http.Request, body []byte, _ string) {
+	var req libremotebuild.JobEventsRequest
+	if len(body) > 0 && !decode(w, body, &req) {
+		return
+	}
+
+	var sent int
+	if lastID := r.Header.Get(libremotebuild.HeaderLastEventID); len(lastID) > 0 {
+		id, err := strconv.Atoi(lastID)
+		if err != nil || id < 0 {
+			sendError(w, http.StatusBadRequest, "Invalid Last-Event-ID")
+			return
+		}
+		sent = id
+	}
+
+	filter := libremotebuild.EventFilter{
+		JobIDs: req.JobIDs,
+		Types:  req.Types,
+	}
+
+	setStatus(w, libremotebuild.ResponseSuccess, "success")
+	w.Header().Set(libremotebuild.HeaderContentType, "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	server.mx.Lock()
+	retry := server.EventRetry
+	server.mx.Unlock()
+	if retry > 0 {
+		fmt.Fprintf(w, "retry: %d\n\n", retry.Milliseconds())
+	}
+	flush(w)
+
+	for {
+		server.mx.Lock()
+		var events []libremotebuild.JobEvent
+		if sent < len(server.events) {
+			events = server.events[sent:]
+			sent = len(server.events)
+		}
+		changed := server.changed
+		disconnect := server.disconnect
+		server.mx.Unlock()
+
+		for _, event := range events {
+			if !filter.Matches(event) {
+				continue
+			}
+
+			data, err := json.Marshal(event)
+			if err != nil {
+				return
+			}
+
+			if _, err := fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data); err != nil {
+				return
+			}
+		}
+		flush(w)
+
+		select {
+		case <-changed:
+		case <-disconnect:
+			panic(http.ErrAbortHandler)
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,536 @@
+package remotebuildtest
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	libremotebuild "github.com/RemoteBuild/LibRemotebuild"
+)
+
+// Job a job stored by the server
+type Job struct {
+	Info    libremotebuild.JobInfo
+	Request libremotebuild.AddJobRequest
+	User    string
+	Logs    []LogEntry
+
+	Created time.Time
+
+	artifacts  []artifact
+	script     []libremotebuild.JobState
+	pausedFrom libremotebuild.JobState
+	// position last announced queue position
+	position uint
+}
+
+// LogEntry a single log line of a job
+type LogEntry struct {
+	Time time.Time
+	Text string
+}
+
+// AddJob adds a job directly without a request and returns its ID
+func (server *Server) AddJob(req libremotebuild.AddJobRequest) uint {
+	server.mx.Lock()
+	defer server.mx.Unlock()
+	return server.addJobLocked(req, DefaultUsername).Info.ID
+}
+
+// Job returns a copy of the job with the given ID
+func (server *Server) Job(id uint) (Job, bool) {
+	server.mx.Lock()
+	defer server.mx.Unlock()
+
+	job, ok := server.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+
+	c := *job
+	c.Info = server.infoLocked(job)
+	c.Logs = append([]LogEntry(nil), job.Logs...)
+	return c, true
+}
+
+// SetJobState sets the state of a job
+func (server *Server) SetJobState(id uint, state libremotebuild.JobState) {
+	server.mx.Lock()
+	defer server.mx.Unlock()
+
+	if job, ok := server.jobs[id]; ok {
+		server.setStateLocked(job, state)
+	}
+}
+
+// UpdateJob modifies the info of a job, e.g. to set the exit code
+// or ccache stats before the job finishes
+func (server *Server) UpdateJob(id uint, fn func(info *libremotebuild.JobInfo)) {
+	server.mx.Lock()
+	defer server.mx.Unlock()
+
+	if job, ok := server.jobs[id]; ok {
+		fn(&job.Info)
+		server.notifyLocked()
+	}
+}
+
+// ScriptJob queues state transitions for a job. Each
+// EPJobInfo request applies the next state before answering
+func (server *Server) ScriptJob(id uint, states ...libremotebuild.JobState) {
+	server.mx.Lock()
+	defer server.mx.Unlock()
+
+	if job, ok := server.jobs[id]; ok {
+		job.script = append(job.script, states...)
+	}
+}
+
+// AppendLog appends lines to the log of a job
+func (server *Server) AppendLog(id uint, lines ...string) {
+	server.mx.Lock()
+	defer server.mx.Unlock()
+
+	job, ok := server.jobs[id]
+	if !ok {
+		return
+	}
+
+	for _, line := range lines {
+		job.Logs = append(job.Logs, LogEntry{
+			Time: time.Now(),
+			Text: line,
+		})
+	}
+
+	server.notifyLocked()
+}
+
+func (server *Server) addJobLocked(req libremotebuild.AddJobRequest, user string) *Job {
+	now := time.Now()
+	job := &Job{
+		Info: libremotebuild.JobInfo{
+			ID:         server.nextID,
+			Info:       jobDescription(req),
+			BuildType:  req.Type,
+			UploadType: req.UploadType,
+			Status:     libremotebuild.JobWaiting,
+			Created:    now,
+			User:       user,
+			Args:       libremotebuild.RedactArgs(req.Args),
+		},
+		Request: req,
+		User:    user,
+		Created: now,
+	}
+
+	server.jobs[job.Info.ID] = job
+	server.nextID++
+
+	job.position = server.infoLocked(job).Position
+	server.emitLocked(job, libremotebuild.JobCreatedEvent, libremotebuild.JobWaiting)
+	server.notifyLocked()
+
+	return job
+}
+
+// jobDescription returns the info shown for a job
+func jobDescription(req libremotebuild.AddJobRequest) string {
+	switch req.Type {
+	case libremotebuild.JobGit:
+		return req.Args[libremotebuild.GitURL]
+	case libremotebuild.JobLocal:
+		return "local upload " + req.Args[libremotebuild.LocalUploadID]
+	}
+
+	return req.Args[libremotebuild.AURPackage]
+}
+
+func (server *Server) setStateLocked(job *Job, state libremotebuild.JobState) {
+	now := time.Now()
+
+	if state == libremotebuild.JobRunning && job.Info.Status != libremotebuild.JobRunning {
+		job.Info.RunningSince = now
+		if job.Info.Started.IsZero() {
+			job.Info.Started = now
+		}
+		if len(job.Info.Worker) == 0 {
+			job.Info.Worker = DefaultWorker
+		}
+	}
+	if state.IsTerminal() && !job.Info.RunningSince.IsZero() {
+		job.Info.Duration = now.Sub(job.Info.RunningSince)
+	}
+	if state.IsTerminal() && !job.Info.Status.IsTerminal() {
+		job.Info.Finished = now
+		finishLocked(job, state)
+	}
+
+	if state == libremotebuild.JobDone && job.Info.Status != libremotebuild.JobDone {
+		server.publishLocked(job)
+	}
+
+	prev := job.Info.Status
+	job.Info.Status = state
+
+	if prev != state {
+		server.emitLocked(job, libremotebuild.JobStateChangedEvent, prev)
+		if state.IsTerminal() && !prev.IsTerminal() {
+			server.emitLocked(job, libremotebuild.JobFinishedEvent, prev)
+		}
+		server.updatePositionsLocked()
+	}
+
+	server.notifyLocked()
+}
+
+// finishLocked sets the exit code and failure reason of
+// a job entering state, unless they were set by UpdateJob
+func finishLocked(job *Job, state libremotebuild.JobState) {
+	if job.Info.ExitCode == nil && (state == libremotebuild.JobDone || state == libremotebuild.JobFailed) {
+		code := 0
+		if state == libremotebuild.JobFailed {
+			code = 1
+		}
+		job.Info.ExitCode = &code
+	}
+
+	if state == libremotebuild.JobFailed && len(job.Info.FailureReason) == 0 {
+		job.Info.FailureReason = "build failed"
+	}
+}
+
+// infoLocked returns the info of job including its queue position
+func (server *Server) infoLocked(job *Job) libremotebuild.JobInfo {
+	info := job.Info
+	info.Position = 0
+
+	info.Artifacts, info.Packages = nil, nil
+	for _, a := range job.artifacts {
+		info.Artifacts = append(info.Artifacts, a.Artifact)
+		if pkg, ok := libremotebuild.ParsePackageFile(a.Name); ok {
+			info.Packages = append(info.Packages, pkg)
+		}
+	}
+
+	if info.Status == libremotebuild.JobWaiting {
+		for _, other := range server.jobs {
+			if other.Info.Status == libremotebuild.JobWaiting && other.Info.ID <= info.ID {
+				info.Position++
+			}
+		}
+	}
+
+	return info
+}
+
+// sortedJobsLocked returns all jobs ordered by ID
+func (server *Server) sortedJobsLocked() []*Job {
+	jobs := make([]*Job, 0, len(server.jobs))
+	for _, job := range server.jobs {
+		jobs = append(jobs, job)
+	}
+
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].Info.ID < jobs[j].Info.ID
+	})
+
+	return jobs
+}
+
+func (server *Server) addJob(w http.ResponseWriter, r *http.Request, body []byte, user string) {
+	var req libremotebuild.AddJobRequest
+	if !decode(w, body, &req) {
+		return
+	}
+
+	if req.Type == libremotebuild.JobNoBuild {
+		sendError(w, http.StatusBadRequest, "Invalid job type")
+		return
+	}
+
+	if err := req.Type.ValidateArgs(req.Args); err != nil {
+		sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := req.UploadType.ValidateArgs(req.Args); err != nil {
+		sendError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if repo := libremotebuild.PacmanRepoFromArgs(req.Args); repo != nil {
+		if err := repo.Validate(); err != nil {
+			sendError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	for _, webhook := range req.Webhooks {
+		if err := webhook.Validate(); err != nil {
+			sendError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	key := req.IdempotencyKey
+	if len(key) == 0 {
+		key = r.Header.Get(libremotebuild.HeaderIdempotencyKey)
+	}
+
+	server.mx.Lock()
+	defer server.mx.Unlock()
+
+	for _, dep := range req.DependsOn {
+		if _, ok := server.jobs[dep]; !ok {
+			sendError(w, http.StatusBadRequest, "Unknown dependency")
+			return
+		}
+	}
+
+	if req.Type == libremotebuild.JobLocal {
+		if _, ok := server.uploads[req.Args[libremotebuild.LocalUploadID]]; !ok {
+			sendError(w, http.StatusBadRequest, "Unknown upload")
+			return
+		}
+	}
+
+	// Return already created job
+	job, ok := server.jobs[server.idempotency[key]]
+	if len(key) == 0 || !ok {
+		job = server.addJobLocked(req, user)
+		if len(key) > 0 {
+			server.idempotency[key] = job.Info.ID
+		}
+	}
+
+	sendResponse(w, libremotebuild.AddJobResponse{
+		ID:             job.Info.ID,
+		Position:       int(server.infoLocked(job).Position),
+		IdempotencyKey: key,
+	})
+}
+
+func (server *Server) listJobs(w http.ResponseWriter, r *http.Request, body []byte, _ string) {
+	var req libremotebuild.ListJobsRequest
+	if !decode(w, body, &req) {
+		return
+	}
+
+	offset := req.Offset
+	if len(req.Cursor) > 0 {
+		var err error
+		if offset, err = strconv.Atoi(req.Cursor); err != nil || offset < 0 {
+			sendError(w, http.StatusBadRequest, "Invalid cursor")
+			return
+		}
+	}
+
+	server.mx.Lock()
+	defer server.mx.Unlock()
+
+	var jobs []*Job
+	for _, job := range server.sortedJobsLocked() {
+		if matchesQuery(job, req) {
+			jobs = append(jobs, job)
+		}
+	}
+
+	sortJobs(jobs, req.SortBy, req.Descending)
+	total := len(jobs)
+
+	// Apply pagination
+	if offset > len(jobs) {
+		offset = len(jobs)
+	}
+	jobs = jobs[offset:]
+	if req.Limit > 0 && len(jobs) > req.Limit {
+		jobs = jobs[:req.Limit]
+	}
+
+	response := libremotebuild.ListJobsResponse{
+		Jobs:  make([]libremotebuild.JobInfo, 0, len(jobs)),
+		Total: total,
+	}
+	for _, job := range jobs {
+		response.Jobs = append(response.Jobs, server.infoLocked(job))
+	}
+
+	if next := offset + len(jobs); next < total {
+		response.NextCursor = strconv.Itoa(next)
+	}
+
+	sendResponse(w, response)
+}
+
+// matchesQuery returns true if job matches all filters of req
+func matchesQuery(job *Job, req libremotebuild.ListJobsRequest) bool {
+	if len(req.States) > 0 && !containsState(req.States, job.Info.Status) {
+		return false
+	}
+	if len(req.Types) > 0 && !containsType(req.Types, job.Info.BuildType) {
+		return false
+	}
+	if len(req.UploadTypes) > 0 && !containsUploadType(req.UploadTypes, job.Info.UploadType) {
+		return false
+	}
+	if req.Since != nil && job.Created.Before(*req.Since) {
+		return false
+	}
+	if req.Until != nil && job.Created.After(*req.Until) {
+		return false
+	}
+	if len(req.Package) > 0 && job.Info.Info != req.Package {
+		return false
+	}
+
+	return true
+}
+
+func containsState(states []libremotebuild.JobState, state libremotebuild.JobState) bool {
+	for _, s := range states {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
+
+func containsType(types []libremotebuild.JobType, jobType libremotebuild.JobType) bool {
+	for _, t := range types {
+		if t == jobType {
+			return true
+		}
+	}
+	return false
+}
+
+func containsUploadType(types []libremotebuild.UploadType, uploadType libremotebuild.UploadType) bool {
+	for _, t := range types {
+		if t == uploadType {
+			return true
+		}
+	}
+	return false
+}
+
+// sortJobs sorts jobs by key. Without a key
+// jobs are sorted newest first
+func sortJobs(jobs []*Job, key libremotebuild.JobSortKey, desc bool) {
+	if len(key) == 0 {
+		key = libremotebuild.SortByID
+		desc = true
+	}
+
+	less := func(a, b *Job) bool {
+		switch key {
+		case libremotebuild.SortByCreated:
+			return a.Created.Before(b.Created)
+		case libremotebuild.SortByState:
+			return a.Info.Status < b.Info.Status
+		case libremotebuild.SortByDuration:
+			return a.Info.Duration < b.Info.Duration
+		}
+		return a.Info.ID < b.Info.ID
+	}
+
+	sort.SliceStable(jobs, func(i, j int) bool {
+		if desc {
+			return less(jobs[j], jobs[i])
+		}
+		return less(jobs[i], jobs[j])
+	})
+}
+
+// requestedJob returns the job referenced by a JobRequest. Sends
+// an error response and returns nil if it doesn't exist
+func (server *Server) requestedJob(w http.ResponseWriter, body []byte) *Job {
+	var req libremotebuild.JobRequest
+	if !decode(w, body, &req) {
+		return nil
+	}
+
+	job, ok := server.jobs[req.JobID]
+	if !ok {
+		sendError(w, http.StatusNotFound, "Job not found")
+		return nil
+	}
+
+	return job
+}
+
+func (server *Server) jobInfo(w http.ResponseWriter, r *http.Request, body []byte, _ string) {
+	server.mx.Lock()
+	defer server.mx.Unlock()
+
+	job := server.requestedJob(w, body)
+	if job == nil {
+		return
+	}
+
+	// Apply scripted transition
+	if len(job.script) > 0 {
+		server.setStateLocked(job, job.script[0])
+		job.script = job.script[1:]
+	}
+
+	sendResponse(w, server.infoLocked(job))
+}
+
+func (server *Server) cancelJob(w http.ResponseWriter, r *http.Request, body []byte, _ string) {
+	server.mx.Lock()
+	defer server.mx.Unlock()
+
+	job := server.requestedJob(w, body)
+	if job == nil {
+		return
+	}
+
+	if job.Info.Status.IsTerminal() {
+		sendError(w, http.StatusConflict, "Invalid state: job already finished")
+		return
+	}
+
+	server.setStateLocked(job, libremotebuild.JobCancelled)
+	sendSuccess(w, "success")
+}
+
+func (server *Server) pauseJob(w http.ResponseWriter, r *http.Request, body []byte, _ string) {
+	server.mx.Lock()
+	defer server.mx.Unlock()
+
+	job := server.requestedJob(w, body)
+	if job == nil {
+		return
+	}
+
+	switch job.Info.Status {
+	case libremotebuild.JobWaiting, libremotebuild.JobRunning:
+	default:
+		sendError(w, http.StatusConflict, "Invalid state: can't pause job")
+		return
+	}
+
+	job.pausedFrom = job.Info.Status
+	server.setStateLocked(job, libremotebuild.JobPaused)
+	sendSuccess(w, "success")
+}
+
+func (server *Server) resumeJob(w http.ResponseWriter, r *http.Request, body []byte, _ string) {
+	server.mx.Lock()
+	defer server.mx.Unlock()
+
+	job := server.requestedJob(w, body)
+	if job == nil {
+		return
+	}
+
+	if job.Info.Status != libremotebuild.JobPaused {
+		sendError(w, http.StatusConflict, "Invalid state: job not paused")
+		return
+	}
+
+	server.setStateLocked(job, job.pausedFrom)
+	sendSuccess(w, "success")
+}
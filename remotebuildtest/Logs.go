@@ -0,0 +1,91 @@
+package remotebuildtest
+
+import (
+	"io"
+	"net/http"
+
+	libremotebuild "github.com/RemoteBuild/LibRemotebuild"
+)
+
+// notifyLocked wakes up all waiting log and event streams
+func (server *Server) notifyLocked() {
+	if server.changed != nil {
+		close(server.changed)
+	}
+	server.changed = make(chan struct{})
+}
+
+// jobLogs streams all log lines newer than the requested time. The
+// stream stays open until the job reaches a terminal state
+func (server *Server) jobLogs(w http.ResponseWriter, r *http.Request, body []byte, _ string) {
+	var req libremotebuild.JobLogsRequest
+	if !decode(w, body, &req) {
+		return
+	}
+
+	server.mx.Lock()
+	job, ok := server.jobs[req.JobID]
+	server.mx.Unlock()
+
+	if !ok {
+		sendError(w, http.StatusNotFound, "Job not found")
+		return
+	}
+
+	setStatus(w, libremotebuild.ResponseSuccess, "success")
+	w.Header().Set(libremotebuild.HeaderContentType, "text/plain")
+	w.WriteHeader(http.StatusOK)
+	flush(w)
+
+	var sent uint
+	for {
+		server.mx.Lock()
+		entries := job.Logs[sent:]
+		sent = uint(len(job.Logs))
+		done := job.Info.Status.IsTerminal()
+		changed := server.changed
+		disconnect := server.disconnect
+		cutLine := server.cutLine
+		server.mx.Unlock()
+
+		for _, entry := range entries {
+			if !entry.Time.After(req.Since) {
+				continue
+			}
+
+			// Send half of the line and drop the connection
+			if cutLine {
+				server.mx.Lock()
+				server.cutLine = false
+				server.mx.Unlock()
+
+				io.WriteString(w, entry.Text[:len(entry.Text)/2])
+				flush(w)
+				panic(http.ErrAbortHandler)
+			}
+
+			if _, err := io.WriteString(w, entry.Text+"\n"); err != nil {
+				return
+			}
+		}
+		flush(w)
+
+		if done {
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-disconnect:
+			panic(http.ErrAbortHandler)
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func flush(w http.ResponseWriter) {
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
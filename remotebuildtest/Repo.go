@@ -0,0 +1,63 @@
+package remotebuildtest
+
+import (
+	"sort"
+
+	libremotebuild "github.com/RemoteBuild/LibRemotebuild"
+)
+
+// RepoPackages returns the package files in the pacman repository name
+func (server *Server) RepoPackages(name string) []string {
+	server.mx.Lock()
+	defer server.mx.Unlock()
+
+	var files []string
+	for _, file := range server.repos[name] {
+		files = append(files, file)
+	}
+
+	sort.Strings(files)
+	return files
+}
+
+// publishLocked adds the package artifacts of a finished
+// job to its pacman repository, like repo-add would
+func (server *Server) publishLocked(job *Job) {
+	repo := libremotebuild.PacmanRepoFromArgs(job.Request.Args)
+	if repo == nil {
+		return
+	}
+
+	state := &libremotebuild.RepositoryState{
+		Name: repo.Name,
+	}
+	job.Info.Repository = state
+
+	db, ok := server.repos[repo.Name]
+	if !ok {
+		db = make(map[string]string)
+		server.repos[repo.Name] = db
+	}
+
+	for _, a := range job.artifacts {
+		pkg, ok := libremotebuild.ParsePackageFile(a.Name)
+		if !ok {
+			continue
+		}
+
+		if old, ok := db[pkg.Name]; ok && old != a.Name && repo.RemoveOld {
+			state.Removed = append(state.Removed, old)
+		}
+
+		db[pkg.Name] = a.Name
+		state.Added = append(state.Added, a.Name)
+	}
+
+	if len(state.Added) == 0 {
+		state.Error = "no packages to publish"
+		return
+	}
+
+	state.Published = true
+	state.Signed = repo.Sign
+}
@@ -0,0 +1,415 @@
+// Package remotebuildtest provides an in-process fake RemoteBuild
+// server speaking the X-Response-Status header protocol
+package remotebuildtest
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"time"
+
+	libremotebuild "github.com/RemoteBuild/LibRemotebuild"
+)
+
+// Default credentials of the user created by NewServer
+const (
+	DefaultUsername = "test"
+	DefaultPassword = "test"
+)
+
+// DefaultWorker worker reported for jobs started by the server
+const DefaultWorker = "worker-1"
+
+// Server a fake RemoteBuild server keeping all state in memory
+type Server struct {
+	*httptest.Server
+
+	mx          sync.Mutex
+	users       map[string]string
+	sessions    map[string]string
+	jobs        map[uint]*Job
+	nextID      uint
+	idempotency map[string]uint
+	uploads     map[string][]byte
+	repos       map[string]map[string]string
+	events      []libremotebuild.JobEvent
+	failures    map[libremotebuild.Endpoint][]*Failure
+	requests    []RecordedRequest
+	changed     chan struct{}
+	disconnect  chan struct{}
+	cutLine     bool
+	cutArtifact int64
+
+	// IgnoreRange ignore Range headers of artifact downloads
+	IgnoreRange bool
+	// EventRetry reconnect delay sent to event stream clients
+	EventRetry time.Duration
+	// CcacheStats returned by EPCcacheStats
+	CcacheStats string
+	// CcacheClears amount of EPCcacheClear calls
+	CcacheClears int
+}
+
+// Failure an injected failure for an endpoint
+type Failure struct {
+	HTTPCode int
+	Message  string
+	Headers  map[string]string
+	// Drop closes the connection without sending a response
+	Drop bool
+	// Times amount of requests to fail. Defaults to 1
+	Times int
+}
+
+// RecordedRequest a request received by the server
+type RecordedRequest struct {
+	Method   string
+	Endpoint libremotebuild.Endpoint
+	Header   http.Header
+	Body     []byte
+	Time     time.Time
+}
+
+// NewServer starts a new fake server with the default user
+func NewServer() *Server {
+	server := newServer()
+	server.Server = httptest.NewServer(server.handler())
+	return server
+}
+
+// NewTLSServer starts a new fake server using TLS with a self signed certificate
+func NewTLSServer() *Server {
+	server := newServer()
+	server.Server = httptest.NewTLSServer(server.handler())
+	return server
+}
+
+func newServer() *Server {
+	return &Server{
+		users: map[string]string{
+			DefaultUsername: DefaultPassword,
+		},
+		sessions:    make(map[string]string),
+		jobs:        make(map[uint]*Job),
+		nextID:      1,
+		idempotency: make(map[string]uint),
+		uploads:     make(map[string][]byte),
+		repos:       make(map[string]map[string]string),
+		failures:    make(map[libremotebuild.Endpoint][]*Failure),
+		changed:     make(chan struct{}),
+		disconnect:  make(chan struct{}),
+		CcacheStats: "cache hit rate 0.00 %",
+	}
+}
+
+// Config returns a RequestConfig for the server, logged in as the default user
+func (server *Server) Config() *libremotebuild.RequestConfig {
+	return &libremotebuild.RequestConfig{
+		URL:          server.URL,
+		IgnoreCert:   server.TLS != nil,
+		Username:     DefaultUsername,
+		MachineID:    "remotebuildtest",
+		SessionToken: server.NewSession(DefaultUsername),
+	}
+}
+
+// LibRB returns a LibRB using Config()
+func (server *Server) LibRB() *libremotebuild.LibRB {
+	return libremotebuild.NewLibRB(server.Config())
+}
+
+// AddUser adds or replaces a user
+func (server *Server) AddUser(username, password string) {
+	server.mx.Lock()
+	defer server.mx.Unlock()
+	server.users[username] = password
+}
+
+// NewSession creates a new session token for username
+func (server *Server) NewSession(username string) string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	token := hex.EncodeToString(b)
+
+	server.mx.Lock()
+	server.sessions[token] = username
+	server.mx.Unlock()
+
+	return token
+}
+
+// ExpireSession invalidates a session token
+func (server *Server) ExpireSession(token string) {
+	server.mx.Lock()
+	defer server.mx.Unlock()
+	delete(server.sessions, token)
+}
+
+// Fail injects f for the next requests to ep
+func (server *Server) Fail(ep libremotebuild.Endpoint, f Failure) {
+	if f.Times <= 0 {
+		f.Times = 1
+	}
+
+	server.mx.Lock()
+	defer server.mx.Unlock()
+	server.failures[ep] = append(server.failures[ep], &f)
+}
+
+// Requests returns all requests received so far
+func (server *Server) Requests() []RecordedRequest {
+	server.mx.Lock()
+	defer server.mx.Unlock()
+	return append([]RecordedRequest(nil), server.requests...)
+}
+
+// RequestsTo returns all requests received for ep
+func (server *Server) RequestsTo(ep libremotebuild.Endpoint) []RecordedRequest {
+	var reqs []RecordedRequest
+	for _, req := range server.Requests() {
+		if req.Endpoint == ep {
+			reqs = append(reqs, req)
+		}
+	}
+
+	return reqs
+}
+
+// DisconnectStreams aborts all open log streams
+func (server *Server) DisconnectStreams() {
+	server.mx.Lock()
+	defer server.mx.Unlock()
+
+	close(server.disconnect)
+	server.disconnect = make(chan struct{})
+}
+
+// DisconnectStreamsMidLine makes the next log line sent by any
+// log stream get cut in half before the connection is dropped
+func (server *Server) DisconnectStreamsMidLine() {
+	server.mx.Lock()
+	defer server.mx.Unlock()
+
+	server.cutLine = true
+}
+
+// CutArtifactDownload makes the next artifact download
+// drop the connection after n bytes were sent
+func (server *Server) CutArtifactDownload(n int64) {
+	server.mx.Lock()
+	defer server.mx.Unlock()
+
+	server.cutArtifact = n
+}
+
+// endpointHandler handles a request. user is
+// empty for endpoints not requiring a session
+type endpointHandler func(w http.ResponseWriter, r *http.Request, body []byte, user string)
+
+func (server *Server) handler() http.Handler {
+	mux := http.NewServeMux()
+
+	server.handle(mux, libremotebuild.EPPing, libremotebuild.POST, false, server.ping)
+	server.handle(mux, libremotebuild.EPLogin, libremotebuild.POST, false, server.login)
+	server.handle(mux, libremotebuild.EPRegister, libremotebuild.POST, false, server.register)
+
+	server.handle(mux, libremotebuild.EPJobAdd, libremotebuild.PUT, true, server.addJob)
+	server.handle(mux, libremotebuild.EPJobs, libremotebuild.GET, true, server.listJobs)
+	server.handle(mux, libremotebuild.EPJobInfo, libremotebuild.GET, true, server.jobInfo)
+	server.handle(mux, libremotebuild.EPJobCancel, libremotebuild.POST, true, server.cancelJob)
+	server.handle(mux, libremotebuild.EPJobPause, libremotebuild.PUT, true, server.pauseJob)
+	server.handle(mux, libremotebuild.EPJobResume, libremotebuild.PUT, true, server.resumeJob)
+	server.handle(mux, libremotebuild.EPJobLogs, libremotebuild.GET, true, server.jobLogs)
+	server.handle(mux, libremotebuild.EPJobEvents, libremotebuild.GET, true, server.jobEvents)
+	server.handle(mux, libremotebuild.EPJobUpload, libremotebuild.PUT, true, server.uploadSources)
+	server.handle(mux, libremotebuild.EPJobArtifacts, libremotebuild.GET, true, server.listArtifacts)
+	server.handle(mux, libremotebuild.EPJobArtifactDownload, libremotebuild.GET, true, server.downloadArtifact)
+
+	server.handle(mux, libremotebuild.EPCcacheStats, libremotebuild.GET, true, server.ccacheStats)
+	server.handle(mux, libremotebuild.EPCcacheClear, libremotebuild.POST, true, server.ccacheClear)
+
+	return mux
+}
+
+func (server *Server) handle(mux *http.ServeMux, ep libremotebuild.Endpoint, method libremotebuild.Method, auth bool, fn endpointHandler) {
+	mux.HandleFunc(string(ep), func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		server.mx.Lock()
+		server.requests = append(server.requests, RecordedRequest{
+			Method:   r.Method,
+			Endpoint: ep,
+			Header:   r.Header.Clone(),
+			Body:     body,
+			Time:     time.Now(),
+		})
+		failure := server.popFailure(ep)
+		server.mx.Unlock()
+
+		if failure != nil {
+			if failure.Drop {
+				panic(http.ErrAbortHandler)
+			}
+
+			for k, v := range failure.Headers {
+				w.Header().Set(k, v)
+			}
+			sendError(w, failure.HTTPCode, failure.Message)
+			return
+		}
+
+		if r.Method != string(method) {
+			sendError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		var user string
+		if auth {
+			if user = server.authorize(r); len(user) == 0 {
+				sendError(w, http.StatusUnauthorized, "Unauthorized")
+				return
+			}
+		}
+
+		fn(w, r, body, user)
+	})
+}
+
+func (server *Server) popFailure(ep libremotebuild.Endpoint) *Failure {
+	failures := server.failures[ep]
+	if len(failures) == 0 {
+		return nil
+	}
+
+	f := *failures[0]
+	if failures[0].Times--; failures[0].Times <= 0 {
+		server.failures[ep] = failures[1:]
+	}
+
+	return &f
+}
+
+// authorize returns the user of the session used by r
+func (server *Server) authorize(r *http.Request) string {
+	const prefix = string(libremotebuild.Bearer) + " "
+
+	header := r.Header.Get("Authorization")
+	if len(header) <= len(prefix) || header[:len(prefix)] != prefix {
+		return ""
+	}
+
+	server.mx.Lock()
+	defer server.mx.Unlock()
+	return server.sessions[header[len(prefix):]]
+}
+
+func (server *Server) ping(w http.ResponseWriter, r *http.Request, body []byte, _ string) {
+	// Ping accepts an optional session
+	if len(r.Header.Get("Authorization")) > 0 && len(server.authorize(r)) == 0 {
+		sendError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	sendResponse(w, libremotebuild.StringResponse{
+		String: "pong",
+	})
+}
+
+func (server *Server) login(w http.ResponseWriter, r *http.Request, body []byte, _ string) {
+	var req libremotebuild.CredentialsRequest
+	if !decode(w, body, &req) {
+		return
+	}
+
+	server.mx.Lock()
+	password, ok := server.users[req.Username]
+	server.mx.Unlock()
+
+	if !ok || password != req.Password {
+		sendError(w, http.StatusForbidden, "Invalid credentials")
+		return
+	}
+
+	sendResponse(w, libremotebuild.LoginResponse{
+		Token: server.NewSession(req.Username),
+	})
+}
+
+func (server *Server) register(w http.ResponseWriter, r *http.Request, body []byte, _ string) {
+	var req libremotebuild.CredentialsRequest
+	if !decode(w, body, &req) {
+		return
+	}
+
+	if len(req.Username) == 0 || len(req.Password) == 0 {
+		sendError(w, http.StatusBadRequest, "Missing credentials")
+		return
+	}
+
+	server.mx.Lock()
+	defer server.mx.Unlock()
+
+	if _, ok := server.users[req.Username]; ok {
+		sendError(w, http.StatusBadRequest, "User already exists")
+		return
+	}
+
+	server.users[req.Username] = req.Password
+	sendSuccess(w, "success")
+}
+
+func (server *Server) ccacheStats(w http.ResponseWriter, r *http.Request, body []byte, _ string) {
+	server.mx.Lock()
+	defer server.mx.Unlock()
+
+	sendResponse(w, libremotebuild.StringResponse{
+		String: server.CcacheStats,
+	})
+}
+
+func (server *Server) ccacheClear(w http.ResponseWriter, r *http.Request, body []byte, _ string) {
+	server.mx.Lock()
+	defer server.mx.Unlock()
+
+	server.CcacheClears++
+	sendSuccess(w, "Cleared ccache")
+}
+
+// decode parses body into v. Sends an error response on failure
+func decode(w http.ResponseWriter, body []byte, v interface{}) bool {
+	if err := json.Unmarshal(body, v); err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid request: "+err.Error())
+		return false
+	}
+
+	return true
+}
+
+func setStatus(w http.ResponseWriter, status libremotebuild.ResponseStatus, message string) {
+	w.Header().Set(libremotebuild.HeaderStatus, strconv.Itoa(int(status)))
+	w.Header().Set(libremotebuild.HeaderStatusMessage, message)
+}
+
+func sendResponse(w http.ResponseWriter, v interface{}) {
+	setStatus(w, libremotebuild.ResponseSuccess, "success")
+	w.Header().Set(libremotebuild.HeaderContentType, string(libremotebuild.JSONContentType))
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(v)
+}
+
+func sendSuccess(w http.ResponseWriter, message string) {
+	setStatus(w, libremotebuild.ResponseSuccess, message)
+	w.WriteHeader(http.StatusOK)
+}
+
+func sendError(w http.ResponseWriter, code int, message string) {
+	setStatus(w, libremotebuild.ResponseError, message)
+	w.WriteHeader(code)
+}
@@ -0,0 +1,725 @@
+package remotebuildtest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	libremotebuild "github.com/RemoteBuild/LibRemotebuild"
+)
+
+func newTestServer(t *testing.T) (*Server, *libremotebuild.LibRB) {
+	server := NewServer()
+	t.Cleanup(server.Close)
+
+	librb := server.LibRB()
+	librb.WithRetryPolicy(&libremotebuild.BackoffRetry{
+		MaxAttempts: 3,
+		MinDelay:    time.Millisecond,
+		MaxDelay:    time.Millisecond,
+		StatusCodes: []int{http.StatusServiceUnavailable},
+	})
+
+	return server, librb
+}
+
+func TestPingAndLogin(t *testing.T) {
+	_, librb := newTestServer(t)
+
+	if _, err := librb.Ping(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := librb.Register("new", "secret"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := librb.Login("new", "secret"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := librb.Login("new", "wrong"); !errors.Is(err, libremotebuild.ErrUnauthorized) {
+		t.Fatalf("expected ErrUnauthorized, got %v", err)
+	}
+}
+
+func TestAddJobIdempotency(t *testing.T) {
+	server, librb := newTestServer(t)
+
+	req := libremotebuild.AddJobRequest{
+		Type: libremotebuild.JobAUR,
+		Args: map[string]string{
+			libremotebuild.AURPackage: "yay",
+		},
+		IdempotencyKey: libremotebuild.NewIdempotencyKey(),
+	}
+
+	// Drop the connection of the first attempt
+	server.Fail(libremotebuild.EPJobAdd, Failure{Drop: true})
+
+	first, err := librb.SubmitJob(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := librb.SubmitJob(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first.ID != second.ID {
+		t.Fatalf("expected one job, got %d and %d", first.ID, second.ID)
+	}
+	if got := len(server.RequestsTo(libremotebuild.EPJobAdd)); got != 3 {
+		t.Fatalf("expected 3 requests, got %d", got)
+	}
+}
+
+func TestJobErrors(t *testing.T) {
+	server, librb := newTestServer(t)
+
+	if _, err := librb.JobInfo(42); !errors.Is(err, libremotebuild.ErrJobNotFound) {
+		t.Fatalf("expected ErrJobNotFound, got %v", err)
+	}
+
+	id := server.AddJob(libremotebuild.AddJobRequest{Type: libremotebuild.JobAUR})
+	server.SetJobState(id, libremotebuild.JobDone)
+
+	if err := librb.CancelJob(id); !errors.Is(err, libremotebuild.ErrInvalidState) {
+		t.Fatalf("expected ErrInvalidState, got %v", err)
+	}
+
+	server.Fail(libremotebuild.EPJobInfo, Failure{HTTPCode: http.StatusServiceUnavailable, Times: 5})
+	if _, err := librb.JobInfo(id); !errors.Is(err, libremotebuild.ErrServerUnavailable) {
+		t.Fatalf("expected ErrServerUnavailable, got %v", err)
+	}
+}
+
+func TestWaitForJob(t *testing.T) {
+	server, librb := newTestServer(t)
+
+	id := server.AddJob(libremotebuild.AddJobRequest{Type: libremotebuild.JobAUR})
+	server.ScriptJob(id, libremotebuild.JobRunning, libremotebuild.JobRunning, libremotebuild.JobFailed)
+
+	var changes []libremotebuild.JobState
+	info, err := librb.WaitForJob(context.Background(), id, &libremotebuild.WaitOptions{
+		PollInterval: time.Millisecond,
+		OnChange: func(info libremotebuild.JobInfo) {
+			changes = append(changes, info.Status)
+		},
+	})
+
+	var jobErr *libremotebuild.JobError
+	if !errors.As(err, &jobErr) || !errors.Is(err, libremotebuild.ErrJobFailed) {
+		t.Fatalf("expected JobError, got %v", err)
+	}
+	if info.Status != libremotebuild.JobFailed {
+		t.Fatalf("expected failed job, got %s", info.Status)
+	}
+	if len(changes) != 2 {
+		t.Fatalf("expected 2 changes, got %v", changes)
+	}
+}
+
+func TestFollowLogs(t *testing.T) {
+	server, librb := newTestServer(t)
+
+	id := server.AddJob(libremotebuild.AddJobRequest{Type: libremotebuild.JobAUR})
+	server.SetJobState(id, libremotebuild.JobRunning)
+	server.AppendLog(id, "line 1", "line 2")
+
+	follower := librb.FollowLogs(context.Background(), id, time.Time{})
+	defer follower.Close()
+
+	var lines []string
+	for line := range follower.Lines() {
+		lines = append(lines, line.Text)
+
+		switch len(lines) {
+		case 2:
+			// Simulate a network drop
+			server.DisconnectStreams()
+			server.AppendLog(id, "line 3")
+		case 3:
+			server.AppendLog(id, "line 4")
+			server.SetJobState(id, libremotebuild.JobDone)
+		}
+	}
+
+	if err := follower.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(lines, ",") != "line 1,line 2,line 3,line 4" {
+		t.Fatalf("unexpected lines %v", lines)
+	}
+}
+
+func TestFollowLogsDropMidLine(t *testing.T) {
+	server, librb := newTestServer(t)
+
+	id := server.AddJob(libremotebuild.AddJobRequest{Type: libremotebuild.JobAUR})
+	server.SetJobState(id, libremotebuild.JobRunning)
+	server.AppendLog(id, "line 1")
+
+	follower := librb.FollowLogs(context.Background(), id, time.Time{})
+	defer follower.Close()
+
+	var lines []string
+	for line := range follower.Lines() {
+		lines = append(lines, line.Text)
+
+		if len(lines) == 1 {
+			// Both lines are logged before the client reads
+			// on, the second one gets cut by a network drop
+			server.DisconnectStreamsMidLine()
+			server.AppendLog(id, "line 2", "line 3")
+			server.SetJobState(id, libremotebuild.JobDone)
+		}
+	}
+
+	if err := follower.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(lines, ",") != "line 1,line 2,line 3" || follower.Offset() != 3 {
+		t.Fatalf("unexpected lines %v", lines)
+	}
+}
+
+func TestSessionRefresh(t *testing.T) {
+	server, librb := newTestServer(t)
+
+	server.ExpireSession(librb.Config.GetSessionToken())
+	if _, err := librb.ListJobs(0); !errors.Is(err, libremotebuild.ErrUnauthorized) {
+		t.Fatalf("expected ErrUnauthorized, got %v", err)
+	}
+
+	librb.WithTokenRefresher(libremotebuild.LoginRefresher(func(context.Context) (string, string, error) {
+		return DefaultUsername, DefaultPassword, nil
+	}))
+
+	if _, err := librb.ListJobs(0); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRefresherMakingAuthorizedRequests(t *testing.T) {
+	server, librb := newTestServer(t)
+
+	server.ExpireSession(librb.Config.GetSessionToken())
+	librb.WithTokenRefresher(func(ctx context.Context, librb libremotebuild.LibRB) (string, error) {
+		if _, err := librb.ListJobsContext(ctx, 0); err != nil {
+			return "", err
+		}
+		return "", errors.New("unreachable")
+	})
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := librb.ListJobs(0)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, libremotebuild.ErrUnauthorized) {
+			t.Fatalf("expected ErrUnauthorized, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("refresher deadlocked")
+	}
+}
+
+func TestCcache(t *testing.T) {
+	server, librb := newTestServer(t)
+
+	stats, err := librb.QueryCcache()
+	if err != nil || stats.String != server.CcacheStats {
+		t.Fatalf("unexpected stats %q: %v", stats.String, err)
+	}
+
+	if _, err := librb.ClearCcache(); err != nil || server.CcacheClears != 1 {
+		t.Fatalf("ccache not cleared: %v", err)
+	}
+}
+
+func TestIterateJobs(t *testing.T) {
+	server, librb := newTestServer(t)
+
+	for i := 0; i < 7; i++ {
+		id := server.AddJob(libremotebuild.AddJobRequest{Type: libremotebuild.JobAUR})
+		if i%2 == 0 {
+			server.SetJobState(id, libremotebuild.JobDone)
+		}
+	}
+
+	iter := librb.IterateJobs(context.Background(), libremotebuild.ListJobsRequest{
+		Limit:  2,
+		States: []libremotebuild.JobState{libremotebuild.JobDone},
+		SortBy: libremotebuild.SortByID,
+	})
+
+	var ids []uint
+	for iter.Next() {
+		ids = append(ids, iter.Job().ID)
+	}
+
+	if err := iter.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 4 || ids[0] != 1 || ids[3] != 7 {
+		t.Fatalf("unexpected jobs %v", ids)
+	}
+	if got := len(server.RequestsTo(libremotebuild.EPJobs)); got != 2 {
+		t.Fatalf("expected 2 pages, got %d", got)
+	}
+}
+
+func TestLocalBuild(t *testing.T) {
+	server, librb := newTestServer(t)
+
+	dir, err := ioutil.TempDir("", "remotebuildtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"PKGBUILD":           "pkgname=foo",
+		"foo.patch":          "patch",
+		".gitignore":         "*.pkg.tar.*\nsrc/\n",
+		"foo.pkg.tar.zst":    "package",
+		"src/foo.c":          "source",
+		".git/HEAD":          "ref",
+		"secrets/credential": "secret",
+	}
+	for name, content := range files {
+		file := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(file), 0700)
+		if err := ioutil.WriteFile(file, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var uploaded int64
+	resp, err := librb.NewLocalBuild(dir).
+		WithExcludes("secrets/").
+		WithProgress(func(n int64) { uploaded = n }).
+		// Shared options keep uploading the sources
+		WithoutCcache().
+		WithIdempotencyKey(libremotebuild.NewIdempotencyKey()).
+		CreateJob()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	job, _ := server.Job(resp.ID)
+	got, ok := server.UploadedFiles(job.Request.Args[libremotebuild.LocalUploadID])
+	if !ok {
+		t.Fatal("upload not found")
+	}
+
+	want := []string{".gitignore", "PKGBUILD", "foo.patch"}
+	if len(got) != len(want) {
+		t.Fatalf("expected files %v, got %d files", want, len(got))
+	}
+	for _, name := range want {
+		if string(got[name]) != files[name] {
+			t.Errorf("unexpected content of %s: %q", name, got[name])
+		}
+	}
+
+	if uploaded == 0 {
+		t.Error("no progress reported")
+	}
+
+	if _, err := librb.NewLocalBuild(filepath.Join(dir, "src")).CreateJob(); !errors.Is(err, libremotebuild.ErrNoPKGBUILD) {
+		t.Fatalf("expected ErrNoPKGBUILD, got %v", err)
+	}
+}
+
+func TestBatchSubmit(t *testing.T) {
+	server, librb := newTestServer(t)
+
+	batch, err := librb.NewBatch().
+		Add("app", librb.NewAURBuild("app"), "lib").
+		Add("lib", librb.NewAURBuild("lib")).
+		Submit(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app, _ := server.Job(batch.Jobs["app"])
+	if len(app.Request.DependsOn) != 1 || app.Request.DependsOn[0] != batch.Jobs["lib"] {
+		t.Fatalf("unexpected dependencies %v", app.Request.DependsOn)
+	}
+
+	server.SetJobState(batch.Jobs["lib"], libremotebuild.JobDone)
+	server.SetJobState(batch.Jobs["app"], libremotebuild.JobFailed)
+
+	infos, err := batch.Wait(context.Background(), nil)
+	if !errors.Is(err, libremotebuild.ErrJobFailed) {
+		t.Fatalf("expected ErrJobFailed, got %v", err)
+	}
+	if infos["lib"].Status != libremotebuild.JobDone || infos["app"].Status != libremotebuild.JobFailed {
+		t.Fatalf("unexpected states %v", infos)
+	}
+}
+
+func TestDownloadArtifacts(t *testing.T) {
+	server, librb := newTestServer(t)
+
+	dir, err := ioutil.TempDir("", "remotebuildtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	content := bytes.Repeat([]byte("package data "), 1000)
+	id := server.AddJob(libremotebuild.AddJobRequest{Type: libremotebuild.JobAUR})
+	artifact := server.AddArtifact(id, "foo-1.0-1-x86_64.pkg.tar.zst", content)
+
+	// Resume a partial download, with and without range support
+	for _, ignoreRange := range []bool{false, true} {
+		server.IgnoreRange = ignoreRange
+		file := filepath.Join(dir, artifact.Name)
+		if err := ioutil.WriteFile(file+libremotebuild.PartialSuffix, content[:100], 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := librb.DownloadArtifactToFile(id, artifact, file); err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := ioutil.ReadFile(file); !bytes.Equal(got, content) {
+			t.Fatalf("ignoreRange %v: content mismatch", ignoreRange)
+		}
+	}
+
+	// Corrupted partial download
+	server.IgnoreRange = false
+	file := filepath.Join(dir, artifact.Name)
+	os.Remove(file)
+	ioutil.WriteFile(file+libremotebuild.PartialSuffix, []byte("garbage"), 0600)
+	err = librb.DownloadArtifactToFile(id, artifact, file)
+	var verr *libremotebuild.VerificationError
+	if !errors.Is(err, libremotebuild.ErrChecksumMismatch) || !errors.As(err, &verr) {
+		t.Fatalf("expected ErrChecksumMismatch, got %v", err)
+	}
+	for _, f := range []string{file, file + libremotebuild.PartialSuffix} {
+		if _, err := os.Stat(f); !os.IsNotExist(err) {
+			t.Fatalf("corrupted file %s not removed", f)
+		}
+	}
+
+	// Stream to a writer
+	var buf bytes.Buffer
+	if _, err := librb.DownloadArtifact(id, artifact, &buf); err != nil || !bytes.Equal(buf.Bytes(), content) {
+		t.Fatalf("stream failed: %v", err)
+	}
+
+	// Resumed streams are verified too
+	buf.Reset()
+	corrupted := artifact
+	corrupted.SHA256 = strings.Repeat("0", 64)
+	if _, err := librb.DownloadArtifactContext(context.Background(), id, corrupted, &buf, 100); !errors.Is(err, libremotebuild.ErrChecksumMismatch) || buf.Len() > 0 {
+		t.Fatalf("expected ErrChecksumMismatch without data, got %v", err)
+	}
+
+	// A dropped stream is resumed using a Range request
+	server.CutArtifactDownload(1000)
+	if _, err := librb.DownloadArtifactContext(context.Background(), id, artifact, &buf, 100); err == nil || buf.Len() > 0 {
+		t.Fatalf("expected dropped stream to fail without data, got %v", err)
+	}
+	if _, err := librb.DownloadArtifactContext(context.Background(), id, artifact, &buf, 100); err != nil || !bytes.Equal(buf.Bytes(), content[100:]) {
+		t.Fatalf("resumed stream failed: %v", err)
+	}
+	downloads := server.RequestsTo(libremotebuild.EPJobArtifactDownload)
+	if r := downloads[len(downloads)-1].Header.Get("Range"); r != "bytes=1000-" {
+		t.Fatalf("expected Range bytes=1000-, got %q", r)
+	}
+
+	artifacts, err := librb.DownloadArtifacts(id, dir)
+	if err != nil || len(artifacts) != 1 {
+		t.Fatalf("unexpected artifacts %v: %v", artifacts, err)
+	}
+
+	server.AddArtifact(id, "../escape", content)
+	if _, err := librb.DownloadArtifacts(id, dir); !errors.Is(err, libremotebuild.ErrInvalidArtifactName) {
+		t.Fatalf("expected ErrInvalidArtifactName, got %v", err)
+	}
+}
+
+// fakeVerifier accepts signatures equal to its key
+type fakeVerifier struct {
+	key []byte
+}
+
+func (v fakeVerifier) VerifySignature(ctx context.Context, file string, signature []byte) error {
+	if !bytes.Equal(v.key, signature) {
+		return fmt.Errorf("%w: bad key", libremotebuild.ErrSignatureInvalid)
+	}
+	return nil
+}
+
+func TestVerifyArtifactSignatures(t *testing.T) {
+	server, librb := newTestServer(t)
+	librb.WithSignatureVerifier(fakeVerifier{key: []byte("trusted")})
+
+	dir, err := ioutil.TempDir("", "remotebuildtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	content := []byte("package data")
+	id := server.AddJob(libremotebuild.AddJobRequest{Type: libremotebuild.JobAUR})
+	artifact := server.AddArtifact(id, "foo-1.0-1-x86_64.pkg.tar.zst", content)
+	file := filepath.Join(dir, artifact.Name)
+
+	tests := []struct {
+		signature []byte
+		expected  error
+	}{
+		{nil, libremotebuild.ErrSignatureMissing},
+		{[]byte("untrusted"), libremotebuild.ErrSignatureInvalid},
+		{[]byte("trusted"), nil},
+	}
+	for _, test := range tests {
+		artifact = server.SignArtifact(id, artifact.Name, test.signature)
+		err := librb.DownloadArtifactToFile(id, artifact, file)
+		if !errors.Is(err, test.expected) {
+			t.Fatalf("signature %q: expected %v, got %v", test.signature, test.expected, err)
+		}
+
+		_, statErr := os.Stat(file)
+		if (test.expected == nil) != (statErr == nil) {
+			t.Fatalf("signature %q: file exists: %v", test.signature, statErr == nil)
+		}
+		if _, err := os.Stat(file + libremotebuild.PartialSuffix); !os.IsNotExist(err) {
+			t.Fatalf("signature %q: part file left over", test.signature)
+		}
+	}
+
+	// Signatures are listed with the job
+	info, err := librb.JobInfo(id)
+	if err != nil || len(info.Artifacts) != 1 || string(info.Artifacts[0].Signature) != "trusted" {
+		t.Fatalf("unexpected artifacts %+v: %v", info, err)
+	}
+
+	if err := librb.VerifyArtifact(context.Background(), info.Artifacts[0], file); err != nil {
+		t.Fatal(err)
+	}
+
+	// Streams are verified before any data is written
+	var buf bytes.Buffer
+	unsigned := server.SignArtifact(id, artifact.Name, nil)
+	if _, err := librb.DownloadArtifact(id, unsigned, &buf); !errors.Is(err, libremotebuild.ErrSignatureMissing) || buf.Len() > 0 {
+		t.Fatalf("expected ErrSignatureMissing without data, got %v", err)
+	}
+}
+
+func TestPacmanRepo(t *testing.T) {
+	server, librb := newTestServer(t)
+
+	repo := libremotebuild.PacmanRepo{Name: "custom", Sign: true, RemoveOld: true}
+	publish := func(version string) *libremotebuild.RepositoryState {
+		resp, err := librb.NewAURBuild("yay").WithPacmanRepo(repo).CreateJob()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		server.AddArtifact(resp.ID, "yay-"+version+"-x86_64.pkg.tar.zst", []byte(version))
+		server.AddArtifact(resp.ID, "build.log", []byte("log"))
+		server.SetJobState(resp.ID, libremotebuild.JobDone)
+
+		info, err := librb.JobInfo(resp.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Repository == nil || !info.Repository.Published || !info.Repository.Signed {
+			t.Fatalf("repository not published: %+v", info.Repository)
+		}
+		return info.Repository
+	}
+
+	if state := publish("1.0-1"); len(state.Added) != 1 || len(state.Removed) != 0 {
+		t.Fatalf("unexpected state %+v", state)
+	}
+	if state := publish("1.1-1"); len(state.Removed) != 1 || state.Removed[0] != "yay-1.0-1-x86_64.pkg.tar.zst" {
+		t.Fatalf("unexpected state %+v", state)
+	}
+	if pkgs := server.RepoPackages("custom"); len(pkgs) != 1 || pkgs[0] != "yay-1.1-1-x86_64.pkg.tar.zst" {
+		t.Fatalf("unexpected repo packages %v", pkgs)
+	}
+
+	// Invalid settings are rejected before submission
+	_, err := librb.NewAURBuild("yay").WithPacmanRepo(libremotebuild.PacmanRepo{Name: "../custom"}).CreateJob()
+	if !errors.Is(err, libremotebuild.ErrInvalidJobArgs) {
+		t.Fatalf("expected ErrInvalidJobArgs, got %v", err)
+	}
+	_, err = librb.NewAURBuild("yay").WithPacmanRepo(libremotebuild.PacmanRepo{Name: "custom", SignKey: "ABCDEF12"}).CreateJob()
+	if !errors.Is(err, libremotebuild.ErrInvalidJobArgs) {
+		t.Fatalf("expected ErrInvalidJobArgs, got %v", err)
+	}
+}
+
+func TestJobInfoMetadata(t *testing.T) {
+	server, librb := newTestServer(t)
+
+	resp, err := librb.NewAURBuild("yay").
+		WithUploadTarget(libremotebuild.DataManagerTarget{Username: "user", Token: "secret", Host: "dm.example.com"}).
+		CreateJob()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server.SetJobState(resp.ID, libremotebuild.JobRunning)
+	server.AddArtifact(resp.ID, "yay-bin-12.1.0-1-x86_64.pkg.tar.zst", []byte("pkg"))
+	server.UpdateJob(resp.ID, func(info *libremotebuild.JobInfo) {
+		info.Ccache = &libremotebuild.CcacheRun{Hits: 3, Misses: 1}
+	})
+	server.SetJobState(resp.ID, libremotebuild.JobFailed)
+
+	info, err := librb.JobInfo(resp.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Created.IsZero() || info.Started.Before(info.Created) || info.Finished.Before(info.Started) {
+		t.Fatalf("unexpected timestamps %v %v %v", info.Created, info.Started, info.Finished)
+	}
+	if info.User != DefaultUsername || info.Worker != DefaultWorker {
+		t.Fatalf("unexpected user %q or worker %q", info.User, info.Worker)
+	}
+	if info.Args[libremotebuild.DMToken] == "secret" || info.Args[libremotebuild.AURPackage] != "yay" {
+		t.Fatalf("args not redacted: %v", info.Args)
+	}
+	if info.ExitCode == nil || *info.ExitCode != 1 || len(info.FailureReason) == 0 {
+		t.Fatalf("unexpected result %v %q", info.ExitCode, info.FailureReason)
+	}
+
+	expected := libremotebuild.Package{Name: "yay-bin", Version: "12.1.0-1", Arch: "x86_64"}
+	if len(info.Packages) != 1 || info.Packages[0] != expected || len(info.Artifacts) != 1 {
+		t.Fatalf("unexpected packages %v", info.Packages)
+	}
+	if info.Ccache == nil || info.Ccache.HitRate() != 0.75 {
+		t.Fatalf("unexpected ccache stats %+v", info.Ccache)
+	}
+}
+
+func TestJobEvents(t *testing.T) {
+	server, librb := newTestServer(t)
+	server.EventRetry = 10 * time.Millisecond
+
+	first := server.AddJob(libremotebuild.AddJobRequest{Type: libremotebuild.JobAUR})
+	second := server.AddJob(libremotebuild.AddJobRequest{Type: libremotebuild.JobAUR})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	all := librb.JobEvents(ctx, libremotebuild.EventFilter{}, "")
+	defer all.Close()
+	filtered := librb.JobEvents(ctx, libremotebuild.EventFilter{
+		JobIDs: []uint{second},
+		Types:  []libremotebuild.JobEventType{libremotebuild.JobPositionChangedEvent, libremotebuild.JobFinishedEvent},
+	}, "")
+	defer filtered.Close()
+
+	next := func(stream *libremotebuild.EventStream) libremotebuild.JobEvent {
+		event, ok := <-stream.Events()
+		if !ok {
+			t.Fatalf("stream closed: %v", stream.Err())
+		}
+		return event
+	}
+
+	// Replay of the already created jobs
+	for _, id := range []uint{first, second} {
+		if event := next(all); event.Type != libremotebuild.JobCreatedEvent || event.JobID != id {
+			t.Fatalf("unexpected event %+v", event)
+		}
+	}
+
+	server.SetJobState(first, libremotebuild.JobRunning)
+
+	event := next(all)
+	if event.Type != libremotebuild.JobStateChangedEvent || event.PrevState != libremotebuild.JobWaiting || event.State != libremotebuild.JobRunning {
+		t.Fatalf("unexpected event %+v", event)
+	}
+	if event = next(all); event.Type != libremotebuild.JobPositionChangedEvent || event.JobID != second || event.Position != 1 {
+		t.Fatalf("unexpected event %+v", event)
+	}
+
+	// Reconnect after a network drop without losing events
+	server.DisconnectStreams()
+	server.SetJobState(first, libremotebuild.JobDone)
+
+	if event = next(all); event.Type != libremotebuild.JobStateChangedEvent || event.JobID != first {
+		t.Fatalf("unexpected event %+v", event)
+	}
+	if event = next(all); event.Type != libremotebuild.JobFinishedEvent || event.Info == nil || event.Info.Status != libremotebuild.JobDone {
+		t.Fatalf("unexpected event %+v", event)
+	}
+
+	server.SetJobState(second, libremotebuild.JobCancelled)
+
+	// Filtered stream only sees events of the second job
+	for _, expected := range []libremotebuild.JobEventType{libremotebuild.JobPositionChangedEvent, libremotebuild.JobFinishedEvent} {
+		if event = next(filtered); event.Type != expected || event.JobID != second {
+			t.Fatalf("unexpected event %+v", event)
+		}
+	}
+
+	// Resume using the last event ID
+	lastID := all.LastEventID()
+	resumed := librb.JobEvents(ctx, libremotebuild.EventFilter{}, lastID)
+	defer resumed.Close()
+
+	server.AddJob(libremotebuild.AddJobRequest{Type: libremotebuild.JobAUR})
+	if event = next(resumed); event.Type != libremotebuild.JobStateChangedEvent || event.JobID != second {
+		t.Fatalf("unexpected event %+v after %s", event, lastID)
+	}
+}
+
+func TestWebhooks(t *testing.T) {
+	server, librb := newTestServer(t)
+
+	events := make(chan libremotebuild.JobEvent, 10)
+	receiver := httptest.NewServer(libremotebuild.WebhookHandler("secret", func(event libremotebuild.JobEvent) {
+		events <- event
+	}))
+	defer receiver.Close()
+
+	if _, err := librb.NewAURBuild("yay").WithWebhook("ftp://example.com", "secret").CreateJob(); !errors.Is(err, libremotebuild.ErrInvalidWebhook) {
+		t.Fatalf("expected ErrInvalidWebhook, got %v", err)
+	}
+
+	resp, err := librb.NewAURBuild("yay").WithWebhook(receiver.URL, "secret").CreateJob()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the finished event is delivered by default
+	server.SetJobState(resp.ID, libremotebuild.JobRunning)
+	server.SetJobState(resp.ID, libremotebuild.JobDone)
+
+	select {
+	case event := <-events:
+		if event.Type != libremotebuild.JobFinishedEvent || event.JobID != resp.ID || event.State != libremotebuild.JobDone {
+			t.Fatalf("unexpected event %+v", event)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook not delivered")
+	}
+
+	select {
+	case event := <-events:
+		t.Fatalf("unexpected event %+v", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,79 @@
+package remotebuildtest
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	libremotebuild "github.com/RemoteBuild/LibRemotebuild"
+)
+
+// UploadedFiles returns the regular files of an upload by their path
+func (server *Server) UploadedFiles(id string) (map[string][]byte, bool) {
+	server.mx.Lock()
+	archive, ok := server.uploads[id]
+	server.mx.Unlock()
+
+	if !ok {
+		return nil, false
+	}
+
+	files, err := readTarGz(archive)
+	return files, err == nil
+}
+
+func (server *Server) uploadSources(w http.ResponseWriter, r *http.Request, body []byte, _ string) {
+	files, err := readTarGz(body)
+	if err != nil {
+		sendError(w, http.StatusBadRequest, "Invalid archive: "+err.Error())
+		return
+	}
+
+	if _, ok := files["PKGBUILD"]; !ok {
+		sendError(w, http.StatusBadRequest, "Archive contains no PKGBUILD")
+		return
+	}
+
+	id := libremotebuild.NewIdempotencyKey()
+
+	server.mx.Lock()
+	server.uploads[id] = body
+	server.mx.Unlock()
+
+	sendResponse(w, libremotebuild.UploadResponse{
+		ID:   id,
+		Size: int64(len(body)),
+	})
+}
+
+// readTarGz returns all regular files of a tar.gz archive
+func readTarGz(archive []byte) (map[string][]byte, error) {
+	gr, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string][]byte)
+	tr := tar.NewReader(gr)
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		if files[header.Name], err = ioutil.ReadAll(tr); err != nil {
+			return nil, err
+		}
+	}
+}
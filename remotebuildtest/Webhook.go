@@ -0,0 +1,50 @@
+package remotebuildtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	libremotebuild "github.com/RemoteBuild/LibRemotebuild"
+)
+
+// deliverWebhooksLocked sends event to all webhooks of job
+// wanting it. Deliveries happen in the background
+func (server *Server) deliverWebhooksLocked(job *Job, event libremotebuild.JobEvent) {
+	if len(job.Request.Webhooks) == 0 {
+		return
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+
+	for _, webhook := range job.Request.Webhooks {
+		if !webhook.Wants(event.Type) {
+			continue
+		}
+
+		go deliverWebhook(webhook, payload)
+	}
+}
+
+func deliverWebhook(webhook libremotebuild.Webhook, payload []byte) {
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return
+	}
+
+	now := time.Now()
+	req.Header.Set(libremotebuild.HeaderContentType, string(libremotebuild.JSONContentType))
+	req.Header.Set(libremotebuild.HeaderWebhookTimestamp, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(libremotebuild.HeaderWebhookSignature, libremotebuild.SignWebhook(webhook.Secret, now, payload))
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return
+	}
+	resp.Body.Close()
+}